@@ -1,10 +1,16 @@
 package main
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigateway"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsdynamodb"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambdaeventsources"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssecretsmanager"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awssns"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awssqs"
 	"github.com/aws/constructs-go/constructs/v10"
@@ -12,6 +18,7 @@ import (
 )
 
 func setupRoutes(api awsapigateway.LambdaRestApi) {
+	api.Root().AddResource(jsii.String(".well-known"), nil).AddResource(jsii.String("jwks.json"), nil).AddMethod(jsii.String("GET"), nil, nil)
 	api.Root().AddResource(jsii.String("ads"), nil).AddMethod(jsii.String("GET"), nil, nil)
 	api.Root().AddResource(jsii.String("categories"), nil).AddMethod(jsii.String("GET"), nil, nil)
 	api.Root().AddResource(jsii.String("products"), nil).AddResource(jsii.String("{categoryId}"), nil).AddMethod(jsii.String("GET"), nil, nil)
@@ -20,6 +27,9 @@ func setupRoutes(api awsapigateway.LambdaRestApi) {
 	users.AddResource(jsii.String("register"), nil).AddMethod(jsii.String("POST"), nil, nil)
 	users.AddResource(jsii.String("send-otp"), nil).AddMethod(jsii.String("POST"), nil, nil)
 	users.AddResource(jsii.String("verify-otp"), nil).AddMethod(jsii.String("POST"), nil, nil)
+	users.AddResource(jsii.String("refresh"), nil).AddMethod(jsii.String("POST"), nil, nil)
+	users.AddResource(jsii.String("logout"), nil).AddMethod(jsii.String("POST"), nil, nil)
+	users.AddResource(jsii.String("logout-all"), nil).AddMethod(jsii.String("POST"), nil, nil)
 	
 	orders := api.Root().AddResource(jsii.String("orders"), nil)
 	orders.AddMethod(jsii.String("POST"), nil, nil)
@@ -28,16 +38,54 @@ func setupRoutes(api awsapigateway.LambdaRestApi) {
 	orderResource := orders.AddResource(jsii.String("{orderId}"), nil)
 	orderResource.AddMethod(jsii.String("GET"), nil, nil)
 	orderResource.AddResource(jsii.String("cancel"), nil).AddMethod(jsii.String("POST"), nil, nil)
+	orderResource.AddResource(jsii.String("history"), nil).AddMethod(jsii.String("GET"), nil, nil)
 	
 	deliveryAddresses := api.Root().AddResource(jsii.String("delivery-addresses"), nil)
 	deliveryAddresses.AddMethod(jsii.String("POST"), nil, nil)
 	deliveryAddresses.AddMethod(jsii.String("GET"), nil, nil)
+	
+	admin := api.Root().AddResource(jsii.String("admin"), nil)
+	for _, collection := range []struct{ name, id string }{
+		{"categories", "{categoryId}"},
+		{"products", "{productId}"},
+		{"ads", "{adId}"},
+	} {
+		resource := admin.AddResource(jsii.String(collection.name), nil)
+		resource.AddMethod(jsii.String("POST"), nil, nil)
+		item := resource.AddResource(jsii.String(collection.id), nil)
+		item.AddMethod(jsii.String("PUT"), nil, nil)
+		item.AddMethod(jsii.String("DELETE"), nil, nil)
+		if collection.name == "products" {
+			item.AddResource(jsii.String("availability"), nil).AddMethod(jsii.String("PUT"), nil, nil)
+		}
+	}
+	
+	catalog := admin.AddResource(jsii.String("catalog"), nil)
+	catalog.AddResource(jsii.String("import"), nil).AddMethod(jsii.String("POST"), nil, nil)
+	catalog.AddResource(jsii.String("export"), nil).AddMethod(jsii.String("GET"), nil, nil)
+	
+	adminUsers := admin.AddResource(jsii.String("users"), nil)
+	adminUsers.AddResource(jsii.String("{userId}"), nil).AddResource(jsii.String("roles"), nil).AddMethod(jsii.String("PUT"), nil, nil)
 }
 
 type DeliveryStackProps struct {
 	awscdk.StackProps
+	// OrderQueueMaxReceiveCount is the number of times a message is received
+	// from OrderQueue before it is moved to the dead-letter queue.
+	// Defaults to defaultOrderQueueMaxReceiveCount when zero.
+	OrderQueueMaxReceiveCount float64
+	// JwtSigningAlg is the algorithm access tokens are signed with: HS256,
+	// RS256 or ES256. For RS256 and ES256, a key is generated in the
+	// JwtSigningKeys secret at deploy time unless it already names an active
+	// key for the algorithm. Defaults to defaultJwtSigningAlg when empty.
+	JwtSigningAlg string
 }
 
+const (
+	defaultOrderQueueMaxReceiveCount = 5
+	defaultJwtSigningAlg             = "HS256"
+)
+
 // createDynamoTable creates a DynamoDB table with standard configuration
 func createDynamoTable(stack awscdk.Stack, name string) awsdynamodb.Table {
 	return awsdynamodb.NewTable(stack, jsii.String(name), &awsdynamodb.TableProps{
@@ -60,8 +108,21 @@ func grantLambdaTableAccess(table awsdynamodb.Table, lambdaFn awslambda.Function
 
 func NewDeliveryStack(scope constructs.Construct, id string, props *DeliveryStackProps) awscdk.Stack {
 	var sprops awscdk.StackProps
+	maxReceiveCount := float64(defaultOrderQueueMaxReceiveCount)
+	jwtSigningAlg := defaultJwtSigningAlg
 	if props != nil {
 		sprops = props.StackProps
+		if props.OrderQueueMaxReceiveCount > 0 {
+			maxReceiveCount = props.OrderQueueMaxReceiveCount
+		}
+		if props.JwtSigningAlg != "" {
+			jwtSigningAlg = props.JwtSigningAlg
+		}
+	}
+	switch jwtSigningAlg {
+	case "HS256", "RS256", "ES256":
+	default:
+		panic(fmt.Sprintf("unsupported jwtSigningAlg %q: use HS256, RS256 or ES256", jwtSigningAlg))
 	}
 	stack := awscdk.NewStack(scope, &id, &sprops)
 
@@ -76,6 +137,89 @@ func NewDeliveryStack(scope constructs.Construct, id string, props *DeliveryStac
 		"Users":          createDynamoTable(stack, "Users"),
 	}
 
+	// Add GSIs for per-user and per-order lookups
+	tables["Orders"].AddGlobalSecondaryIndex(&awsdynamodb.GlobalSecondaryIndexProps{
+		IndexName: jsii.String("UserOrdersIndex"),
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("userId"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		SortKey: &awsdynamodb.Attribute{
+			Name: jsii.String("createdAt"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		ProjectionType: awsdynamodb.ProjectionType_ALL,
+	})
+
+	tables["DeliveryAddress"].AddGlobalSecondaryIndex(&awsdynamodb.GlobalSecondaryIndexProps{
+		IndexName: jsii.String("UserIndex"),
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("userId"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		ProjectionType: awsdynamodb.ProjectionType_ALL,
+	})
+
+	tables["OrderItems"].AddGlobalSecondaryIndex(&awsdynamodb.GlobalSecondaryIndexProps{
+		IndexName: jsii.String("OrderIndex"),
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("orderId"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		ProjectionType: awsdynamodb.ProjectionType_ALL,
+	})
+
+	tables["Products"].AddGlobalSecondaryIndex(&awsdynamodb.GlobalSecondaryIndexProps{
+		IndexName: jsii.String("CategoryIndex"),
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("categoryId"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		ProjectionType: awsdynamodb.ProjectionType_ALL,
+	})
+
+	// Status history is an item collection per order, sorted by change time
+	tables["OrderStatusHistory"] = awsdynamodb.NewTable(stack, jsii.String("OrderStatusHistory"), &awsdynamodb.TableProps{
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("orderId"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		SortKey: &awsdynamodb.Attribute{
+			Name: jsii.String("sortKey"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		TableName: jsii.String("OrderStatusHistory"),
+	})
+
+	// Stored responses of requests made with an Idempotency-Key, expired by TTL
+	tables["IdempotencyKeys"] = awsdynamodb.NewTable(stack, jsii.String("IdempotencyKeys"), &awsdynamodb.TableProps{
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("id"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		TableName:           jsii.String("IdempotencyKeys"),
+		TimeToLiveAttribute: jsii.String("expiresAt"),
+	})
+
+	// Refresh token families, one item per login, expired by TTL
+	tables["Sessions"] = awsdynamodb.NewTable(stack, jsii.String("Sessions"), &awsdynamodb.TableProps{
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("id"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		TableName:           jsii.String("Sessions"),
+		TimeToLiveAttribute: jsii.String("expiresAt"),
+	})
+
+	tables["Sessions"].AddGlobalSecondaryIndex(&awsdynamodb.GlobalSecondaryIndexProps{
+		IndexName: jsii.String("UserIndex"),
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("userId"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		ProjectionType: awsdynamodb.ProjectionType_KEYS_ONLY,
+	})
+
 	// Add GSI to Users table
 	tables["Users"].AddGlobalSecondaryIndex(&awsdynamodb.GlobalSecondaryIndexProps{
 		IndexName: jsii.String("PhoneIndex"),
@@ -86,9 +230,18 @@ func NewDeliveryStack(scope constructs.Construct, id string, props *DeliveryStac
 		ProjectionType: awsdynamodb.ProjectionType_ALL,
 	})
 
-	// Create SQS queue and SNS topic
+	// Create SQS queue with its dead-letter queue and SNS topic
+	ordersDeadLetterQueue := awssqs.NewQueue(stack, jsii.String("OrderQueueDLQ"), &awssqs.QueueProps{
+		QueueName:       jsii.String("OrderQueueDLQ"),
+		RetentionPeriod: awscdk.Duration_Days(jsii.Number(14)),
+	})
+
 	ordersQueue := awssqs.NewQueue(stack, jsii.String("OrderQueue"), &awssqs.QueueProps{
 		QueueName: jsii.String("OrderQueue"),
+		DeadLetterQueue: &awssqs.DeadLetterQueue{
+			Queue:           ordersDeadLetterQueue,
+			MaxReceiveCount: jsii.Number(maxReceiveCount),
+		},
 	})
 
 	notificationTopic := awssns.NewTopic(stack, jsii.String("OrderStatusNotification"), &awssns.TopicProps{
@@ -104,15 +257,53 @@ func NewDeliveryStack(scope constructs.Construct, id string, props *DeliveryStac
 		"ORDER_ITEMS_TABLE_NAME":    tables["OrderItems"].TableName(),
 		"DELIVERY_ADDRESS_TABLE_NAME": tables["DeliveryAddress"].TableName(),
 		"USERS_TABLE_NAME":          tables["Users"].TableName(),
+		"ORDER_STATUS_HISTORY_TABLE_NAME": tables["OrderStatusHistory"].TableName(),
+		"IDEMPOTENCY_TABLE_NAME":    tables["IdempotencyKeys"].TableName(),
+		"SESSIONS_TABLE_NAME":       tables["Sessions"].TableName(),
 		"ORDER_QUEUE_URL":           ordersQueue.QueueUrl(),
+		"ORDER_DLQ_URL":             ordersDeadLetterQueue.QueueUrl(),
+		"ORDER_QUEUE_MAX_RECEIVE_COUNT": jsii.String(strconv.FormatFloat(maxReceiveCount, 'f', 0, 64)),
 		"ORDER_STATUS_NOTIFICATION_TOPIC_ARN": notificationTopic.TopicArn(),
 	}
 
+	// Key used to sign pagination cursors so clients cannot forge them
+	cursorSigningSecret := awssecretsmanager.NewSecret(stack, jsii.String("CursorSigningSecret"), &awssecretsmanager.SecretProps{
+		GenerateSecretString: &awssecretsmanager.SecretStringGenerator{
+			PasswordLength:     jsii.Number(64),
+			ExcludePunctuation: jsii.Bool(true),
+		},
+	})
+
+	// Key of the OTP hashes stored on users, so a copy of the Users table
+	// is not enough to recover codes
+	otpHashSecret := awssecretsmanager.NewSecret(stack, jsii.String("OtpHashSecret"), &awssecretsmanager.SecretProps{
+		GenerateSecretString: &awssecretsmanager.SecretStringGenerator{
+			PasswordLength:     jsii.Number(64),
+			ExcludePunctuation: jsii.Bool(true),
+		},
+	})
+
+	// JWT signing keys by kid, with "activeKid" naming the HS256 key new
+	// tokens are signed with ("activeKidRS256"/"activeKidES256" for the
+	// asymmetric algorithms). Rotate by adding a key, making it active and
+	// removing the old one once the tokens it signed have expired.
+	jwtSigningKeys := awssecretsmanager.NewSecret(stack, jsii.String("JwtSigningKeys"), &awssecretsmanager.SecretProps{
+		GenerateSecretString: &awssecretsmanager.SecretStringGenerator{
+			SecretStringTemplate: jsii.String(`{"activeKid":"initial"}`),
+			GenerateStringKey:    jsii.String("initial"),
+			PasswordLength:       jsii.Number(64),
+			ExcludePunctuation:   jsii.Bool(true),
+		},
+	})
+
 	// Main API Lambda function
 	apiLambda := awslambda.NewFunction(stack, jsii.String("DeliveryApp"), &awslambda.FunctionProps{
 		Runtime: awslambda.Runtime_PROVIDED_AL2023(),
 		Handler: jsii.String("main"),
 		Code:    awslambda.Code_FromAsset(jsii.String("deliveryAppLambda/function.zip"), nil),
+		// Catalog imports run within the request; API Gateway stops waiting
+		// after 29 seconds.
+		Timeout: awscdk.Duration_Seconds(jsii.Number(29)),
 		Environment: &map[string]*string{
 			"ADS_TABLE_NAME":                    baseEnvVars["ADS_TABLE_NAME"],
 			"CATEGORIES_TABLE_NAME":             baseEnvVars["CATEGORIES_TABLE_NAME"],
@@ -121,40 +312,84 @@ func NewDeliveryStack(scope constructs.Construct, id string, props *DeliveryStac
 			"ORDER_ITEMS_TABLE_NAME":            baseEnvVars["ORDER_ITEMS_TABLE_NAME"],
 			"DELIVERY_ADDRESS_TABLE_NAME":       baseEnvVars["DELIVERY_ADDRESS_TABLE_NAME"],
 			"USERS_TABLE_NAME":                  baseEnvVars["USERS_TABLE_NAME"],
+			"ORDER_STATUS_HISTORY_TABLE_NAME":   baseEnvVars["ORDER_STATUS_HISTORY_TABLE_NAME"],
+			"IDEMPOTENCY_TABLE_NAME":            baseEnvVars["IDEMPOTENCY_TABLE_NAME"],
+			"SESSIONS_TABLE_NAME":               baseEnvVars["SESSIONS_TABLE_NAME"],
 			"ORDER_QUEUE_URL":                   baseEnvVars["ORDER_QUEUE_URL"],
 			"ORDER_STATUS_NOTIFICATION_TOPIC_ARN": baseEnvVars["ORDER_STATUS_NOTIFICATION_TOPIC_ARN"],
-			"JWT_SECRET":                        jsii.String("jwtsecret"), //FIXME: use aws secrets manager in production
+			"JWT_SECRET_ARN":                    jwtSigningKeys.SecretArn(),
+			"JWT_SIGNING_ALG":                   jsii.String(jwtSigningAlg),
+			"CURSOR_SIGNING_SECRET_ARN":         cursorSigningSecret.SecretArn(),
+			"OTP_HASH_SECRET_ARN":               otpHashSecret.SecretArn(),
+			"OTP_SENDER":                        jsii.String("sns"),
+			"OTP_LENGTH":                        jsii.String("6"),
 		},
 	})
 
 	// Order processor Lambda function
 	orderProcessorLambda := awslambda.NewFunction(stack, jsii.String("OrderProcessor"), &awslambda.FunctionProps{
 		Runtime: awslambda.Runtime_PROVIDED_AL2023(),
-		Handler: jsii.String("handlers.ProcessOrderQueue"),
-		Code:    awslambda.Code_FromAsset(jsii.String("deliveryAppLambda/function.zip"), nil),
+		Handler: jsii.String("bootstrap"),
+		Code:    awslambda.Code_FromAsset(jsii.String("deliveryAppLambda/orderprocessor.zip"), nil),
 		Environment: &map[string]*string{
 			"ORDERS_TABLE_NAME":                 baseEnvVars["ORDERS_TABLE_NAME"],
 			"ORDER_ITEMS_TABLE_NAME":            baseEnvVars["ORDER_ITEMS_TABLE_NAME"],
 			"DELIVERY_ADDRESS_TABLE_NAME":       baseEnvVars["DELIVERY_ADDRESS_TABLE_NAME"],
 			"USERS_TABLE_NAME":                  baseEnvVars["USERS_TABLE_NAME"],
 			"ORDER_QUEUE_URL":                   baseEnvVars["ORDER_QUEUE_URL"],
+			"ORDER_DLQ_URL":                     baseEnvVars["ORDER_DLQ_URL"],
+			"ORDER_QUEUE_MAX_RECEIVE_COUNT":     baseEnvVars["ORDER_QUEUE_MAX_RECEIVE_COUNT"],
 			"ORDER_STATUS_NOTIFICATION_TOPIC_ARN": baseEnvVars["ORDER_STATUS_NOTIFICATION_TOPIC_ARN"],
 		},
 	})
 
 	// Grant permissions to API Lambda
-	grantLambdaTableAccess(tables["Ads"], apiLambda, true) // Read-only
-	grantLambdaTableAccess(tables["Categories"], apiLambda, true) // Read-only
-	grantLambdaTableAccess(tables["Products"], apiLambda, true) // Read-only
+	grantLambdaTableAccess(tables["Ads"], apiLambda, false) // Read-write
+	grantLambdaTableAccess(tables["Categories"], apiLambda, false) // Read-write
+	grantLambdaTableAccess(tables["Products"], apiLambda, false) // Read-write
 	grantLambdaTableAccess(tables["Orders"], apiLambda, false) // Read-write
 	grantLambdaTableAccess(tables["OrderItems"], apiLambda, false) // Read-write
 	grantLambdaTableAccess(tables["DeliveryAddress"], apiLambda, false) // Read-write
 	grantLambdaTableAccess(tables["Users"], apiLambda, false) // Read-write
+	grantLambdaTableAccess(tables["OrderStatusHistory"], apiLambda, false) // Read-write
+	grantLambdaTableAccess(tables["IdempotencyKeys"], apiLambda, false) // Read-write
+	grantLambdaTableAccess(tables["Sessions"], apiLambda, false) // Read-write
+
+	jwtSigningKeys.GrantRead(apiLambda, nil)
+	if jwtSigningAlg != defaultJwtSigningAlg {
+		// The secret is seeded with an HS256 key only; generate a key for
+		// the asymmetric algorithm before the API starts signing with it.
+		jwtKeyGenerator := awslambda.NewFunction(stack, jsii.String("JwtKeyGenerator"), &awslambda.FunctionProps{
+			Runtime: awslambda.Runtime_PROVIDED_AL2023(),
+			Handler: jsii.String("bootstrap"),
+			Code:    awslambda.Code_FromAsset(jsii.String("deliveryAppLambda/jwtkeygen.zip"), nil),
+			Timeout: awscdk.Duration_Seconds(jsii.Number(30)),
+		})
+		jwtSigningKeys.GrantRead(jwtKeyGenerator, nil)
+		jwtSigningKeys.GrantWrite(jwtKeyGenerator)
+
+		jwtKey := awscdk.NewCustomResource(stack, jsii.String("JwtAsymmetricKey"), &awscdk.CustomResourceProps{
+			ServiceToken: jwtKeyGenerator.FunctionArn(),
+			Properties: &map[string]interface{}{
+				"SecretArn": jwtSigningKeys.SecretArn(),
+				"Algorithm": jwtSigningAlg,
+			},
+		})
+		apiLambda.Node().AddDependency(jwtKey)
+	}
+	cursorSigningSecret.GrantRead(apiLambda, nil)
+	otpHashSecret.GrantRead(apiLambda, nil)
 	
 	ordersQueue.GrantSendMessages(apiLambda)
 	ordersQueue.GrantConsumeMessages(apiLambda)
 	notificationTopic.GrantPublish(apiLambda)
 
+	// OTPs are sent as SMS, which SNS only allows publishing to "*"
+	apiLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("sns:Publish"),
+		Resources: jsii.Strings("*"),
+	}))
+
 	// Grant permissions to Order Processor Lambda
 	grantLambdaTableAccess(tables["Orders"], orderProcessorLambda, false) // Read-write
 	grantLambdaTableAccess(tables["OrderItems"], orderProcessorLambda, false) // Read-write
@@ -162,8 +397,15 @@ func NewDeliveryStack(scope constructs.Construct, id string, props *DeliveryStac
 	grantLambdaTableAccess(tables["Users"], orderProcessorLambda, false) // Read-write
 	
 	ordersQueue.GrantConsumeMessages(orderProcessorLambda)
+	ordersDeadLetterQueue.GrantSendMessages(orderProcessorLambda)
 	notificationTopic.GrantPublish(orderProcessorLambda)
 
+	// Trigger the Order Processor Lambda from the order queue
+	orderProcessorLambda.AddEventSource(awslambdaeventsources.NewSqsEventSource(ordersQueue, &awslambdaeventsources.SqsEventSourceProps{
+		BatchSize:               jsii.Number(10),
+		ReportBatchItemFailures: jsii.Bool(true),
+	}))
+
 	// Create API Gateway
 	apiGateway := awsapigateway.NewLambdaRestApi(stack, jsii.String("DeliveryAppApi"), &awsapigateway.LambdaRestApiProps{
 		Handler: apiLambda,
@@ -180,6 +422,16 @@ func NewDeliveryStack(scope constructs.Construct, id string, props *DeliveryStac
 		Description: jsii.String("URL of the API Gateway"),
 	})
 
+	awscdk.NewCfnOutput(stack, jsii.String("OrderQueueDLQUrl"), &awscdk.CfnOutputProps{
+		Value:       ordersDeadLetterQueue.QueueUrl(),
+		Description: jsii.String("URL of the OrderQueue dead-letter queue"),
+	})
+
+	awscdk.NewCfnOutput(stack, jsii.String("UsersTableName"), &awscdk.CfnOutputProps{
+		Value:       tables["Users"].TableName(),
+		Description: jsii.String("Name of the Users table, for admintool"),
+	})
+
 	return stack
 }
 
@@ -189,14 +441,38 @@ func main() {
 	app := awscdk.NewApp(nil)
 
 	NewDeliveryStack(app, "DeliveryStack", &DeliveryStackProps{
-		awscdk.StackProps{
+		StackProps: awscdk.StackProps{
 			Env: env(),
 		},
+		OrderQueueMaxReceiveCount: orderQueueMaxReceiveCount(app),
+		JwtSigningAlg:             jwtSigningAlg(app),
 	})
 
 	app.Synth(nil)
 }
 
+// orderQueueMaxReceiveCount reads the optional "orderQueueMaxReceiveCount"
+// context value, e.g. `cdk deploy -c orderQueueMaxReceiveCount=3`.
+func orderQueueMaxReceiveCount(app awscdk.App) float64 {
+	switch v := app.Node().TryGetContext(jsii.String("orderQueueMaxReceiveCount")).(type) {
+	case float64:
+		return v
+	case string:
+		count, err := strconv.ParseFloat(v, 64)
+		if err == nil {
+			return count
+		}
+	}
+	return 0
+}
+
+// jwtSigningAlg reads the optional "jwtSigningAlg" context value, e.g.
+// `cdk deploy -c jwtSigningAlg=RS256`.
+func jwtSigningAlg(app awscdk.App) string {
+	alg, _ := app.Node().TryGetContext(jsii.String("jwtSigningAlg")).(string)
+	return alg
+}
+
 func env() *awscdk.Environment {
 	return nil
 }
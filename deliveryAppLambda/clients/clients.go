@@ -0,0 +1,122 @@
+// Package clients holds the AWS service clients shared by every invocation of
+// a Lambda container.
+package clients
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// Clients is the set of AWS service clients used by the app.
+type Clients struct {
+	DynamoDB *dynamodb.Client
+	SQS      *sqs.Client
+	SNS      *sns.Client
+
+	SecretsManager *secretsmanager.Client
+}
+
+// Options customizes how the clients are built. Empty endpoints use the
+// default AWS endpoints.
+type Options struct {
+	DynamoDBEndpoint string
+	SQSEndpoint      string
+	SNSEndpoint      string
+
+	SecretsManagerEndpoint string
+	// ConfigOptions are passed to config.LoadDefaultConfig, e.g. to set the
+	// region or static credentials for a local stand-in.
+	ConfigOptions []func(*config.LoadOptions) error
+}
+
+var (
+	mu      sync.Mutex
+	current *Clients
+	options = optionsFromEnv()
+)
+
+// optionsFromEnv reads endpoint overrides so a deployed or local binary can be
+// pointed at stand-ins without code changes.
+func optionsFromEnv() Options {
+	return Options{
+		DynamoDBEndpoint: os.Getenv("DYNAMODB_ENDPOINT_URL"),
+		SQSEndpoint:      os.Getenv("SQS_ENDPOINT_URL"),
+		SNSEndpoint:      os.Getenv("SNS_ENDPOINT_URL"),
+
+		SecretsManagerEndpoint: os.Getenv("SECRETSMANAGER_ENDPOINT_URL"),
+	}
+}
+
+// Configure replaces the options used to build the clients and drops any
+// clients built so far. Call it before the first Get.
+func Configure(opts Options) {
+	mu.Lock()
+	defer mu.Unlock()
+	options = opts
+	current = nil
+}
+
+// Set injects a prebuilt client set, e.g. in tests.
+func Set(c *Clients) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = c
+}
+
+// Get returns the shared clients, loading the AWS config and building them on
+// first use. A failed load is retried on the next call.
+func Get() (*Clients, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if current != nil {
+		return current, nil
+	}
+
+	c, err := New(context.TODO(), options)
+	if err != nil {
+		return nil, err
+	}
+	current = c
+	return current, nil
+}
+
+// New builds a client set from a freshly loaded AWS config.
+func New(ctx context.Context, opts Options) (*Clients, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, opts.ConfigOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS SDK config: %v", err)
+	}
+
+	return &Clients{
+		DynamoDB: dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+			if opts.DynamoDBEndpoint != "" {
+				o.BaseEndpoint = aws.String(opts.DynamoDBEndpoint)
+			}
+		}),
+		SQS: sqs.NewFromConfig(cfg, func(o *sqs.Options) {
+			if opts.SQSEndpoint != "" {
+				o.BaseEndpoint = aws.String(opts.SQSEndpoint)
+			}
+		}),
+		SNS: sns.NewFromConfig(cfg, func(o *sns.Options) {
+			if opts.SNSEndpoint != "" {
+				o.BaseEndpoint = aws.String(opts.SNSEndpoint)
+			}
+		}),
+		SecretsManager: secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+			if opts.SecretsManagerEndpoint != "" {
+				o.BaseEndpoint = aws.String(opts.SecretsManagerEndpoint)
+			}
+		}),
+	}, nil
+}
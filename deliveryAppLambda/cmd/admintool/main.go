@@ -0,0 +1,155 @@
+// Command admintool manages user roles directly in the Users table. It is how
+// the first admin is created; after that, admins can change roles through
+// PUT /admin/users/{userId}/roles.
+//
+// Usage:
+//
+//	admintool [flags] show -phone <phone>
+//	admintool [flags] grant -phone <phone> -role <role>
+//	admintool [flags] revoke -phone <phone> -role <role>
+//
+// The table name defaults to the USERS_TABLE_NAME environment variable (see
+// the UsersTableName stack output). Use -endpoint to point the tool at
+// DynamoDB Local. Role changes reach the user's access token on its next
+// refresh.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/clients"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/database"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	flags := flag.NewFlagSet("admintool", flag.ExitOnError)
+	table := flags.String("users-table", os.Getenv("USERS_TABLE_NAME"), "name of the Users table")
+	endpoint := flags.String("endpoint", "", "custom DynamoDB endpoint URL, e.g. http://localhost:8000")
+	region := flags.String("region", "", "AWS region (defaults to the SDK configuration)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: admintool [flags] show|grant|revoke -phone <phone> [-role <role>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *table == "" {
+		fail(errors.New("users table is required (-users-table or USERS_TABLE_NAME)"))
+	}
+
+	ctx := context.Background()
+	client, err := newClient(ctx, *endpoint, *region)
+	if err != nil {
+		fail(err)
+	}
+	users := database.NewRepositoriesWithClient(client, database.Tables{UsersTable: *table}).Users
+
+	command := flags.Arg(0)
+	args := flags.Args()[1:]
+	switch command {
+	case "show":
+		err = show(users, args)
+	case "grant", "revoke":
+		err = changeRole(users, command, args)
+	default:
+		err = fmt.Errorf("unknown command %q", command)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "admintool: %v\n", err)
+	os.Exit(1)
+}
+
+func newClient(ctx context.Context, endpoint, region string) (*dynamodb.Client, error) {
+	opts := clients.Options{DynamoDBEndpoint: endpoint}
+	if region != "" {
+		opts.ConfigOptions = append(opts.ConfigOptions, config.WithRegion(region))
+	}
+
+	c, err := clients.New(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.DynamoDB, nil
+}
+
+func show(users models.UserRepository, args []string) error {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	phone := flags.String("phone", "", "phone number of the user")
+	flags.Parse(args)
+
+	user, err := findUser(users, *phone)
+	if err != nil {
+		return err
+	}
+
+	printUser(user.ID, user.Name, user.RoleNames())
+	return nil
+}
+
+func changeRole(users models.UserRepository, command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	phone := flags.String("phone", "", "phone number of the user")
+	role := flags.String("role", "", "role to "+command)
+	flags.Parse(args)
+
+	if *role == "" {
+		return fmt.Errorf("%s: -role is required", command)
+	}
+
+	user, err := findUser(users, *phone)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, name := range user.RoleNames() {
+		if name != *role {
+			names = append(names, name)
+		}
+	}
+	if command == "grant" {
+		names = append(names, *role)
+	}
+
+	roles, err := models.SetUserRoles(users, user.ID, names)
+	if err != nil {
+		return err
+	}
+
+	user.Roles = roles
+	printUser(user.ID, user.Name, user.RoleNames())
+	return nil
+}
+
+func findUser(users models.UserRepository, phone string) (*models.User, error) {
+	if phone == "" {
+		return nil, errors.New("-phone is required")
+	}
+
+	user, err := users.GetByPhone(phone)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", phone, err)
+	}
+	return user, nil
+}
+
+func printUser(id, name string, roles []string) {
+	fmt.Printf("%s\t%s\t%s\n", id, name, strings.Join(roles, ","))
+}
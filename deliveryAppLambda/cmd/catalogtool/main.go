@@ -0,0 +1,184 @@
+// Command catalogtool imports and exports the catalog of categories and
+// products as CSV or JSON files.
+//
+// Usage:
+//
+//	catalogtool [flags] import -file <catalog.csv|catalog.json> [-format csv|json] [-dry-run]
+//	catalogtool [flags] export [-format csv|json] [-file <path>]
+//
+// An import makes the stored catalog match the file: items are matched by
+// SKU, missing ones are created, changed ones updated and stored items absent
+// from the file deleted. -dry-run only prints those counts. The format
+// defaults to the file extension, and exports go to stdout unless -file is
+// given.
+//
+// The table names default to the CATEGORIES_TABLE_NAME and
+// PRODUCTS_TABLE_NAME environment variables. Use -endpoint to point the tool
+// at DynamoDB Local.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/clients"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/database"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	flags := flag.NewFlagSet("catalogtool", flag.ExitOnError)
+	categoriesTable := flags.String("categories-table", os.Getenv("CATEGORIES_TABLE_NAME"), "name of the Categories table")
+	productsTable := flags.String("products-table", os.Getenv("PRODUCTS_TABLE_NAME"), "name of the Products table")
+	endpoint := flags.String("endpoint", "", "custom DynamoDB endpoint URL, e.g. http://localhost:8000")
+	region := flags.String("region", "", "AWS region (defaults to the SDK configuration)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: catalogtool [flags] import|export [-file path] [-format csv|json] [-dry-run]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *categoriesTable == "" || *productsTable == "" {
+		fail(errors.New("table names are required (-categories-table and -products-table, or CATEGORIES_TABLE_NAME and PRODUCTS_TABLE_NAME)"))
+	}
+
+	ctx := context.Background()
+	client, err := newClient(ctx, *endpoint, *region)
+	if err != nil {
+		fail(err)
+	}
+	repos := database.NewRepositoriesWithClient(client, database.Tables{
+		CategoriesTable: *categoriesTable,
+		ProductsTable:   *productsTable,
+	})
+
+	switch command := flags.Arg(0); command {
+	case "import":
+		err = importCatalog(repos, flags.Args()[1:])
+	case "export":
+		err = exportCatalog(repos, flags.Args()[1:])
+	default:
+		err = fmt.Errorf("unknown command %q", command)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	var validationErr *models.CatalogValidationError
+	if errors.As(err, &validationErr) {
+		fmt.Fprintln(os.Stderr, "catalogtool: invalid catalog:")
+		for _, problem := range validationErr.Problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", problem)
+		}
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "catalogtool: %v\n", err)
+	os.Exit(1)
+}
+
+func newClient(ctx context.Context, endpoint, region string) (*dynamodb.Client, error) {
+	opts := clients.Options{DynamoDBEndpoint: endpoint}
+	if region != "" {
+		opts.ConfigOptions = append(opts.ConfigOptions, config.WithRegion(region))
+	}
+
+	c, err := clients.New(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.DynamoDB, nil
+}
+
+// fileFormat returns the explicit format, or the one named by the file
+// extension.
+func fileFormat(format, path string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return models.CatalogFormatCSV
+	}
+	return models.CatalogFormatJSON
+}
+
+func importCatalog(repos *models.Repositories, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	path := flags.String("file", "", "catalog file to import")
+	format := flags.String("format", "", "csv or json (defaults to the file extension)")
+	dryRun := flags.Bool("dry-run", false, "print the changes without writing them")
+	flags.Parse(args)
+
+	if *path == "" {
+		return errors.New("import: -file is required")
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	catalog, err := models.DecodeCatalog(file, fileFormat(*format, *path))
+	if err != nil {
+		return err
+	}
+
+	plan, err := models.PlanCatalogImport(repos.Categories, repos.Products, catalog)
+	if err != nil {
+		return err
+	}
+
+	printDiff("categories", plan.Diff.Categories)
+	printDiff("products", plan.Diff.Products)
+	if *dryRun {
+		fmt.Println("dry run, nothing was written")
+		return nil
+	}
+
+	return plan.Apply(repos.Categories, repos.Products)
+}
+
+func printDiff(name string, counts models.ChangeCounts) {
+	fmt.Printf("%-10s create %d, update %d, delete %d, unchanged %d\n",
+		name, counts.Create, counts.Update, counts.Delete, counts.Unchanged)
+}
+
+func exportCatalog(repos *models.Repositories, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	path := flags.String("file", "", "file to write (defaults to stdout)")
+	format := flags.String("format", "", "csv or json (defaults to the file extension, or json)")
+	flags.Parse(args)
+
+	catalog, err := models.ExportCatalog(repos.Categories, repos.Products)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *path != "" {
+		file, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return models.EncodeCatalog(out, fileFormat(*format, *path), catalog)
+}
@@ -0,0 +1,283 @@
+// Command dlqtool inspects and redrives messages in the OrderQueue
+// dead-letter queue.
+//
+// Usage:
+//
+//	dlqtool [flags] list
+//	dlqtool [flags] redrive -all
+//	dlqtool [flags] redrive -ids <messageId>[,<messageId>...]
+//
+// The queue URLs default to the ORDER_DLQ_URL and ORDER_QUEUE_URL environment
+// variables. Use -endpoint to point the tool at any SQS-compatible service.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/clients"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/services"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// inspectVisibilityTimeout hides received messages from other consumers while
+// the tool works on them. Messages that are not redriven are released again.
+const inspectVisibilityTimeout = 60
+
+func main() {
+	flags := flag.NewFlagSet("dlqtool", flag.ExitOnError)
+	dlqURL := flags.String("dlq-url", os.Getenv("ORDER_DLQ_URL"), "URL of the dead-letter queue")
+	queueURL := flags.String("queue-url", os.Getenv("ORDER_QUEUE_URL"), "URL of the queue to redrive messages to")
+	endpoint := flags.String("endpoint", "", "custom SQS endpoint URL, e.g. http://localhost:9324")
+	region := flags.String("region", "", "AWS region (defaults to the SDK configuration)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: dlqtool [flags] list|redrive [-all | -ids id1,id2]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *dlqURL == "" {
+		fail(errors.New("dead-letter queue URL is required (-dlq-url or ORDER_DLQ_URL)"))
+	}
+
+	ctx := context.Background()
+	client, err := newClient(ctx, *endpoint, *region)
+	if err != nil {
+		fail(err)
+	}
+
+	switch command := flags.Arg(0); command {
+	case "list":
+		err = list(ctx, client, *dlqURL)
+	case "redrive":
+		err = redrive(ctx, client, *dlqURL, *queueURL, flags.Args()[1:])
+	default:
+		err = fmt.Errorf("unknown command %q", command)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "dlqtool: %v\n", err)
+	os.Exit(1)
+}
+
+func newClient(ctx context.Context, endpoint, region string) (*sqs.Client, error) {
+	opts := clients.Options{SQSEndpoint: endpoint}
+	if region != "" {
+		opts.ConfigOptions = append(opts.ConfigOptions, config.WithRegion(region))
+	}
+
+	c, err := clients.New(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.SQS, nil
+}
+
+// deadLetter is a dead-lettered message decoded for display.
+type deadLetter struct {
+	Message      types.Message
+	Order        services.OrderMessage
+	ReceiveCount string
+	Reason       string
+}
+
+func decode(message types.Message) deadLetter {
+	letter := deadLetter{
+		Message:      message,
+		ReceiveCount: message.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)],
+	}
+
+	if attr, ok := message.MessageAttributes[services.FailureReasonAttribute]; ok && attr.StringValue != nil {
+		letter.Reason = *attr.StringValue
+	}
+
+	err := json.Unmarshal([]byte(aws.ToString(message.Body)), &letter.Order)
+	if err != nil && letter.Reason == "" {
+		letter.Reason = fmt.Sprintf("invalid message body: %v", err)
+	}
+
+	if letter.Reason == "" {
+		letter.Reason = "unknown (moved by the queue redrive policy)"
+	}
+
+	return letter
+}
+
+// receiveAll drains every visible message from the queue, hiding each for
+// inspectVisibilityTimeout seconds.
+func receiveAll(ctx context.Context, client *sqs.Client, queueURL string) ([]types.Message, error) {
+	var messages []types.Message
+	seen := map[string]bool{}
+
+	for {
+		result, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:                    aws.String(queueURL),
+			MaxNumberOfMessages:         10,
+			WaitTimeSeconds:             1,
+			VisibilityTimeout:           inspectVisibilityTimeout,
+			MessageAttributeNames:       []string{"All"},
+			MessageSystemAttributeNames: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameAll},
+		})
+		if err != nil {
+			return messages, fmt.Errorf("failed to receive messages from SQS: %v", err)
+		}
+
+		if len(result.Messages) == 0 {
+			return messages, nil
+		}
+
+		for _, message := range result.Messages {
+			id := aws.ToString(message.MessageId)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			messages = append(messages, message)
+		}
+	}
+}
+
+// release makes messages visible again so they stay in the queue untouched.
+func release(ctx context.Context, client *sqs.Client, queueURL string, messages []types.Message) error {
+	for start := 0; start < len(messages); start += 10 {
+		end := min(start+10, len(messages))
+
+		var entries []types.ChangeMessageVisibilityBatchRequestEntry
+		for i, message := range messages[start:end] {
+			entries = append(entries, types.ChangeMessageVisibilityBatchRequestEntry{
+				Id:                aws.String(fmt.Sprintf("m%d", i)),
+				ReceiptHandle:     message.ReceiptHandle,
+				VisibilityTimeout: 0,
+			})
+		}
+
+		_, err := client.ChangeMessageVisibilityBatch(ctx, &sqs.ChangeMessageVisibilityBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to release messages: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func list(ctx context.Context, client *sqs.Client, dlqURL string) error {
+	messages, err := receiveAll(ctx, client, dlqURL)
+	defer release(ctx, client, dlqURL, messages)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MESSAGE ID\tRECEIVES\tORDER ID\tSTATUS\tUSER ID\tFAILURE REASON")
+	for _, message := range messages {
+		letter := decode(message)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			aws.ToString(message.MessageId),
+			letter.ReceiveCount,
+			letter.Order.OrderId,
+			letter.Order.Status,
+			letter.Order.UserId,
+			letter.Reason,
+		)
+	}
+	w.Flush()
+
+	fmt.Printf("%d message(s) in dead-letter queue\n", len(messages))
+	return nil
+}
+
+func redrive(ctx context.Context, client *sqs.Client, dlqURL, queueURL string, args []string) error {
+	flags := flag.NewFlagSet("redrive", flag.ExitOnError)
+	all := flags.Bool("all", false, "redrive every message")
+	ids := flags.String("ids", "", "comma separated message IDs to redrive")
+	flags.Parse(args)
+
+	if queueURL == "" {
+		return errors.New("target queue URL is required (-queue-url or ORDER_QUEUE_URL)")
+	}
+
+	selected := map[string]bool{}
+	for _, id := range strings.Split(*ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			selected[id] = true
+		}
+	}
+	if !*all && len(selected) == 0 {
+		return errors.New("redrive requires -all or -ids")
+	}
+
+	messages, err := receiveAll(ctx, client, dlqURL)
+	if err != nil {
+		release(ctx, client, dlqURL, messages)
+		return err
+	}
+
+	var skipped []types.Message
+	redriven := 0
+	for i, message := range messages {
+		id := aws.ToString(message.MessageId)
+		if !*all && !selected[id] {
+			skipped = append(skipped, message)
+			continue
+		}
+		delete(selected, id)
+
+		_, err = client.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:    aws.String(queueURL),
+			MessageBody: message.Body,
+		})
+		if err != nil {
+			release(ctx, client, dlqURL, append(skipped, messages[i:]...))
+			return fmt.Errorf("failed to redrive message %s: %v", id, err)
+		}
+
+		_, err = client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String(dlqURL),
+			ReceiptHandle: message.ReceiptHandle,
+		})
+		if err != nil {
+			release(ctx, client, dlqURL, append(skipped, messages[i+1:]...))
+			return fmt.Errorf("message %s was redriven but could not be deleted from the dead-letter queue: %v", id, err)
+		}
+
+		redriven++
+		fmt.Printf("redriven %s\n", id)
+	}
+
+	if err := release(ctx, client, dlqURL, skipped); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d message(s) redriven to %s\n", redriven, queueURL)
+
+	if !*all && len(selected) > 0 {
+		var missing []string
+		for id := range selected {
+			missing = append(missing, id)
+		}
+		return fmt.Errorf("message(s) not found in dead-letter queue: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
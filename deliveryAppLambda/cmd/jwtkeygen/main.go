@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/clients"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/utils"
+	"github.com/aws/aws-lambda-go/cfn"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// main is the entrypoint of the CloudFormation custom resource that adds an
+// RS256 or ES256 key to the JwtSigningKeys secret when the stack is deployed
+// with that algorithm and the secret has no active key for it yet.
+func main() {
+	lambda.Start(cfn.LambdaWrap(ensureSigningKey))
+}
+
+func ensureSigningKey(ctx context.Context, event cfn.Event) (string, map[string]interface{}, error) {
+	secretARN, _ := event.ResourceProperties["SecretArn"].(string)
+	alg, _ := event.ResourceProperties["Algorithm"].(string)
+	physicalID := secretARN + "/" + alg
+
+	// Keys are left in the secret on delete; tokens signed with them may
+	// still be in use, and removing keys is part of rotation.
+	if event.RequestType == cfn.RequestDelete {
+		return event.PhysicalResourceID, nil, nil
+	}
+	if secretARN == "" || alg == "" {
+		return physicalID, nil, errors.New("SecretArn and Algorithm are required")
+	}
+
+	c, err := clients.Get()
+	if err != nil {
+		return physicalID, nil, err
+	}
+
+	output, err := c.SecretsManager.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretARN),
+	})
+	if err != nil {
+		return physicalID, nil, fmt.Errorf("failed to read JWT keys: %v", err)
+	}
+
+	updated, changed, err := utils.EnsureActiveKey(aws.ToString(output.SecretString), alg)
+	if err != nil {
+		return physicalID, nil, err
+	}
+	if !changed {
+		return physicalID, nil, nil
+	}
+
+	_, err = c.SecretsManager.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(secretARN),
+		SecretString: aws.String(updated),
+	})
+	if err != nil {
+		return physicalID, nil, fmt.Errorf("failed to store JWT keys: %v", err)
+	}
+
+	log.Printf("generated an active %s key in %s", alg, secretARN)
+	return physicalID, nil, nil
+}
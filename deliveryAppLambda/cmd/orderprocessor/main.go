@@ -0,0 +1,23 @@
+package main
+
+import (
+	"log"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/database"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/handlers"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/services"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// main is the entrypoint of the OrderProcessor Lambda, which is triggered by
+// the OrderQueue event source mapping.
+func main() {
+	repos, err := database.NewRepositories()
+	if err != nil {
+		log.Fatalf("failed to set up repositories: %v", err)
+	}
+
+	// The processor never sends OTPs.
+	h := handlers.NewHandler(repos, services.SQSOrderQueue{}, nil)
+	lambda.Start(h.ProcessOrderQueue)
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/google/uuid"
+)
+
+type AdRepository struct {
+	client *dynamodb.Client
+	table  string
+}
+
+func (r *AdRepository) ListAll(page models.PageRequest) (*models.Page[models.Ad], error) {
+	return scanPage[models.Ad](r.client, &dynamodb.ScanInput{
+		TableName: &r.table,
+	}, "ads", page)
+}
+
+func (r *AdRepository) GetById(adId string) (*models.Ad, error) {
+	return getItem[models.Ad](r.client, r.table, adId, models.ErrAdNotFound)
+}
+
+func (r *AdRepository) Create(ad models.Ad) (*models.Ad, error) {
+	if ad.Id == "" {
+		ad.Id = uuid.New().String()
+	}
+
+	err := putItem(r.client, r.table, ad)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ad, nil
+}
+
+func (r *AdRepository) Update(ad models.Ad) error {
+	return replaceItem(r.client, r.table, ad, models.ErrAdNotFound)
+}
+
+func (r *AdRepository) Delete(adId string) error {
+	return deleteItem(r.client, r.table, adId, models.ErrAdNotFound)
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// batchWriteMaxItems is the largest number of requests BatchWriteItem accepts.
+const batchWriteMaxItems = 25
+
+// batchWrite runs write requests against one table in BatchWriteItem chunks,
+// retrying unprocessed items like GetByIds retries unprocessed keys.
+func batchWrite(client *dynamodb.Client, table string, requests []types.WriteRequest) error {
+	for start := 0; start < len(requests); start += batchWriteMaxItems {
+		end := min(start+batchWriteMaxItems, len(requests))
+		requestItems := map[string][]types.WriteRequest{
+			table: requests[start:end],
+		}
+
+		for attempt := 0; len(requestItems) > 0; attempt++ {
+			if attempt == maxBatchAttempts {
+				return fmt.Errorf("failed to write to %s: items still unprocessed after %d attempts", table, attempt)
+			}
+			if attempt > 0 {
+				time.Sleep(time.Duration(1<<attempt) * 50 * time.Millisecond)
+			}
+
+			result, err := client.BatchWriteItem(context.TODO(), &dynamodb.BatchWriteItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return err
+			}
+			requestItems = result.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// batchPutDelete puts items and deletes the items with deleteIds, keyed by id,
+// in one run of batchWrite.
+func batchPutDelete[T any](client *dynamodb.Client, table string, put []T, deleteIds []string) error {
+	requests := make([]types.WriteRequest, 0, len(put)+len(deleteIds))
+	for _, item := range put {
+		av, err := attributevalue.MarshalMap(item)
+		if err != nil {
+			return err
+		}
+		requests = append(requests, types.WriteRequest{
+			PutRequest: &types.PutRequest{Item: av},
+		})
+	}
+	for _, id := range deleteIds {
+		requests = append(requests, types.WriteRequest{
+			DeleteRequest: &types.DeleteRequest{Key: idKey(id)},
+		})
+	}
+
+	return batchWrite(client, table, requests)
+}
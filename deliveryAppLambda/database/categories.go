@@ -0,0 +1,53 @@
+package database
+
+import (
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/google/uuid"
+)
+
+type CategoryRepository struct {
+	client *dynamodb.Client
+	table  string
+}
+
+// ListAll retrieves one page of categories from the database
+func (r *CategoryRepository) ListAll(page models.PageRequest) (*models.Page[models.Category], error) {
+	return scanPage[models.Category](r.client, &dynamodb.ScanInput{
+		TableName: &r.table,
+	}, "categories", page)
+}
+
+func (r *CategoryRepository) GetById(categoryId string) (*models.Category, error) {
+	return getItem[models.Category](r.client, r.table, categoryId, models.ErrCategoryNotFound)
+}
+
+func (r *CategoryRepository) Create(category models.Category) (*models.Category, error) {
+	if category.Id == "" {
+		category.Id = uuid.New().String()
+	}
+
+	err := putItem(r.client, r.table, category)
+	if err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// Update sets the name and image of a category, so the SKU set by catalog
+// imports survives admin edits.
+func (r *CategoryRepository) Update(category models.Category) error {
+	update := expression.Set(expression.Name("name"), expression.Value(category.Name)).
+		Set(expression.Name("imageUrl"), expression.Value(category.ImageUrl))
+	return updateItem(r.client, r.table, category.Id, update, models.ErrCategoryNotFound)
+}
+
+func (r *CategoryRepository) Delete(categoryId string) error {
+	return deleteItem(r.client, r.table, categoryId, models.ErrCategoryNotFound)
+}
+
+func (r *CategoryRepository) BatchWrite(put []models.Category, deleteIds []string) error {
+	return batchPutDelete(r.client, r.table, put, deleteIds)
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+)
+
+type DeliveryAddressRepository struct {
+	client *dynamodb.Client
+	table  string
+}
+
+func (r *DeliveryAddressRepository) GetById(addressId string) (*models.DeliveryAddress, error) {
+	result, err := r.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: &r.table,
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: addressId},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, models.ErrDeliveryAddressNotFound
+	}
+
+	var address models.DeliveryAddress
+	err = attributevalue.UnmarshalMap(result.Item, &address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &address, nil
+}
+
+func (r *DeliveryAddressRepository) Create(address models.DeliveryAddress) (*models.DeliveryAddress, error) {
+	if address.Id == "" {
+		address.Id = uuid.New().String()
+	}
+
+	item, err := attributevalue.MarshalMap(address)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: &r.table,
+		Item:      item,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &address, nil
+}
+
+func (r *DeliveryAddressRepository) ListByUser(userId string) ([]models.DeliveryAddress, error) {
+	return queryAll[models.DeliveryAddress](r.client, &dynamodb.QueryInput{
+		TableName:              &r.table,
+		IndexName:              aws.String(userDeliveryAddressIndex),
+		KeyConditionExpression: aws.String("userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: userId},
+		},
+	})
+}
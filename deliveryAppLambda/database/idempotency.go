@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type IdempotencyRepository struct {
+	client *dynamodb.Client
+	table  string
+}
+
+func (r *IdempotencyRepository) Claim(id, requestHash string) error {
+	now := time.Now()
+	item, err := attributevalue.MarshalMap(models.IdempotencyRecord{
+		Id:          id,
+		RequestHash: requestHash,
+		Status:      models.IdempotencyInProgress,
+		ExpiresAt:   now.Add(models.IdempotencyKeyTTL).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// DynamoDB deletes expired items lazily, so an expired record may still be
+	// present and is treated as free.
+	_, err = r.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:           &r.table,
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(id) OR expiresAt < :now"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	})
+	if err != nil {
+		var conditionalCheckFailedErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalCheckFailedErr) {
+			return models.ErrIdempotencyKeyExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepository) Get(id string) (*models.IdempotencyRecord, error) {
+	result, err := r.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: &r.table,
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, models.ErrIdempotencyRecordNotFound
+	}
+
+	var record models.IdempotencyRecord
+	err = attributevalue.UnmarshalMap(result.Item, &record)
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (r *IdempotencyRepository) Complete(record models.IdempotencyRecord) error {
+	record.Status = models.IdempotencyCompleted
+	record.ExpiresAt = time.Now().Add(models.IdempotencyKeyTTL).Unix()
+
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: &r.table,
+		Item:      item,
+	})
+	return err
+}
+
+func (r *IdempotencyRepository) Release(id string) error {
+	_, err := r.client.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName: &r.table,
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	return err
+}
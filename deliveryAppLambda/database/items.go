@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// The helpers below implement GetById, Update and Delete for tables keyed by
+// id alone. They report a missing item with the notFound error of the model.
+
+func idKey(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id": &types.AttributeValueMemberS{Value: id},
+	}
+}
+
+func getItem[T any](client *dynamodb.Client, table, id string, notFound error) (*T, error) {
+	result, err := client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: &table,
+		Key:       idKey(id),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, notFound
+	}
+
+	var item T
+	err = attributevalue.UnmarshalMap(result.Item, &item)
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func putItem(client *dynamodb.Client, table string, item interface{}) error {
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: &table,
+		Item:      av,
+	})
+	return err
+}
+
+// replaceItem overwrites an existing item.
+func replaceItem(client *dynamodb.Client, table string, item interface{}, notFound error) error {
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:           &table,
+		Item:                av,
+		ConditionExpression: aws.String("attribute_exists(id)"),
+	})
+	return conditionFailedAs(err, notFound)
+}
+
+// updateItem applies update to an existing item.
+func updateItem(client *dynamodb.Client, table, id string, update expression.UpdateBuilder, notFound error) error {
+	expr, err := expression.NewBuilder().
+		WithUpdate(update).
+		WithCondition(expression.AttributeExists(expression.Name("id"))).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:                 &table,
+		Key:                       idKey(id),
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	return conditionFailedAs(err, notFound)
+}
+
+func deleteItem(client *dynamodb.Client, table, id string, notFound error) error {
+	_, err := client.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName:           &table,
+		Key:                 idKey(id),
+		ConditionExpression: aws.String("attribute_exists(id)"),
+	})
+	return conditionFailedAs(err, notFound)
+}
+
+func conditionFailedAs(err, replacement error) error {
+	var conditionalCheckFailedErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionalCheckFailedErr) {
+		return replacement
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+)
+
+type IdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]models.IdempotencyRecord
+}
+
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{records: map[string]models.IdempotencyRecord{}}
+}
+
+func (r *IdempotencyRepository) Claim(id, requestHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := r.records[id]; ok && existing.ExpiresAt >= now.Unix() {
+		return models.ErrIdempotencyKeyExists
+	}
+
+	r.records[id] = models.IdempotencyRecord{
+		Id:          id,
+		RequestHash: requestHash,
+		Status:      models.IdempotencyInProgress,
+		ExpiresAt:   now.Add(models.IdempotencyKeyTTL).Unix(),
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) Get(id string) (*models.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.records[id]
+	if !ok {
+		return nil, models.ErrIdempotencyRecordNotFound
+	}
+	return &record, nil
+}
+
+func (r *IdempotencyRepository) Complete(record models.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record.Status = models.IdempotencyCompleted
+	record.ExpiresAt = time.Now().Add(models.IdempotencyKeyTTL).Unix()
+	r.records[record.Id] = record
+	return nil
+}
+
+func (r *IdempotencyRepository) Release(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.records, id)
+	return nil
+}
@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/google/uuid"
+)
+
+type OrderRepository struct {
+	mu       sync.RWMutex
+	orders   map[string]models.Order
+	items    map[string][]models.OrderItem
+	history  map[string][]models.OrderStatusChange
+	products *ProductRepository
+}
+
+// NewOrderRepository returns an order repository that reserves stock in
+// products.
+func NewOrderRepository(products *ProductRepository) *OrderRepository {
+	return &OrderRepository{
+		orders:   map[string]models.Order{},
+		items:    map[string][]models.OrderItem{},
+		history:  map[string][]models.OrderStatusChange{},
+		products: products,
+	}
+}
+
+func (r *OrderRepository) Create(order models.Order, items []models.OrderItem, actor models.Actor) (*models.Order, []models.OrderItem, error) {
+	if len(items) > models.MaxOrderItems {
+		return nil, nil, models.ErrOrderTooLarge
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if order.Id == "" {
+		order.Id = uuid.New().String()
+	}
+	if _, exists := r.orders[order.Id]; exists {
+		return nil, nil, models.ErrOrderAlreadyExists
+	}
+	if err := r.products.reserve(items); err != nil {
+		return nil, nil, err
+	}
+	if order.Status == "" {
+		order.Status = models.StatusPending
+	}
+	if order.CreatedAt == "" {
+		order.CreatedAt = time.Now().Format(time.RFC3339)
+	}
+
+	savedItems := make([]models.OrderItem, 0, len(items))
+	for _, item := range items {
+		if item.Id == "" {
+			item.Id = uuid.New().String()
+		}
+		item.OrderId = order.Id
+		savedItems = append(savedItems, item)
+	}
+
+	r.orders[order.Id] = order
+	r.items[order.Id] = savedItems
+	r.history[order.Id] = []models.OrderStatusChange{
+		models.NewOrderStatusChange(order.Id, "", order.Status, actor, "order created"),
+	}
+
+	return &order, append([]models.OrderItem(nil), savedItems...), nil
+}
+
+func (r *OrderRepository) GetById(orderId string) (*models.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	order, ok := r.orders[orderId]
+	if !ok {
+		return nil, models.ErrOrderNotFound
+	}
+	return &order, nil
+}
+
+// UpdateStatus checks and applies the transition under one lock, which gives
+// the same outcome as the conditional write of the DynamoDB implementation.
+func (r *OrderRepository) UpdateStatus(orderId string, status models.OrderStatus, actor models.Actor, reason string, restock []models.StockReservation) (*models.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[orderId]
+	if !ok {
+		return nil, models.ErrOrderNotFound
+	}
+
+	if !order.Status.CanTransitionTo(status) {
+		return nil, &models.InvalidTransitionError{From: order.Status, To: status}
+	}
+
+	r.products.restock(restock)
+	r.history[orderId] = append(r.history[orderId], models.NewOrderStatusChange(orderId, order.Status, status, actor, reason))
+	order.Status = status
+	r.orders[orderId] = order
+
+	return &order, nil
+}
+
+// ListByUser returns one page of the orders of a user, newest first.
+func (r *OrderRepository) ListByUser(userId string, page models.PageRequest) (*models.Page[models.Order], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var orders []models.Order
+	for _, order := range sortedValues(r.orders) {
+		if order.UserId == userId {
+			orders = append(orders, order)
+		}
+	}
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].CreatedAt > orders[j].CreatedAt
+	})
+	return paginate(orders, "orders#"+userId, page)
+}
+
+func (r *OrderRepository) GetItems(orderId string) ([]models.OrderItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]models.OrderItem(nil), r.items[orderId]...), nil
+}
+
+// GetStatusHistory returns the history of an order sorted by sortKey, like
+// the query on the history table.
+func (r *OrderRepository) GetStatusHistory(orderId string) ([]models.OrderStatusChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history := append([]models.OrderStatusChange(nil), r.history[orderId]...)
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].SortKey < history[j].SortKey
+	})
+	return history, nil
+}
@@ -0,0 +1,204 @@
+package memory
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+)
+
+var customer = models.Actor{ID: "user-1", Role: models.ActorRoleCustomer}
+
+func stock(n int) *int {
+	return &n
+}
+
+func TestUpdateStatusRestocks(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(products *ProductRepository, productId string)
+		wantStock *int
+	}{
+		{"stock is returned", func(*ProductRepository, string) {}, stock(5)},
+		{
+			name: "product no longer tracks stock",
+			setup: func(products *ProductRepository, productId string) {
+				products.SetAvailability(productId, true, nil)
+			},
+			wantStock: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products := NewProductRepository()
+			orders := NewOrderRepository(products)
+			products.Put(models.Product{Id: "pizza", Available: true, StockQuantity: stock(5)})
+
+			// Two lines of the same product with different options share
+			// its stock.
+			order, items, err := orders.Create(models.Order{UserId: customer.ID}, []models.OrderItem{
+				{ProductId: "pizza", Quantity: 2, StockReserved: true},
+				{ProductId: "pizza", Quantity: 1, StockReserved: true},
+			}, customer)
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if product, _ := products.GetById("pizza"); *product.StockQuantity != 2 {
+				t.Fatalf("stock after Create() = %d, want 2", *product.StockQuantity)
+			}
+
+			tt.setup(products, "pizza")
+			_, err = orders.UpdateStatus(order.Id, models.StatusCanceled, customer, "", models.StockReservations(items))
+			if err != nil {
+				t.Fatalf("UpdateStatus() error = %v", err)
+			}
+
+			product, _ := products.GetById("pizza")
+			switch {
+			case tt.wantStock == nil && product.StockQuantity != nil:
+				t.Errorf("stock = %d, want untracked", *product.StockQuantity)
+			case tt.wantStock != nil && (product.StockQuantity == nil || *product.StockQuantity != *tt.wantStock):
+				t.Errorf("stock = %v, want %d", product.StockQuantity, *tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestCreateReservesStock(t *testing.T) {
+	tests := []struct {
+		name           string
+		items          []models.OrderItem
+		wantOutOfStock []string
+		wantStock      map[string]int
+	}{
+		{
+			name:      "enough stock",
+			items:     []models.OrderItem{{ProductId: "pizza", Quantity: 2, StockReserved: true}, {ProductId: "soup", Quantity: 1, StockReserved: true}},
+			wantStock: map[string]int{"pizza": 1, "soup": 0},
+		},
+		{
+			name:           "lines of one product exceed its stock",
+			items:          []models.OrderItem{{ProductId: "pizza", Quantity: 2, StockReserved: true}, {ProductId: "pizza", Quantity: 2, StockReserved: true}},
+			wantOutOfStock: []string{"pizza"},
+			wantStock:      map[string]int{"pizza": 3, "soup": 1},
+		},
+		{
+			name:           "nothing is taken when one product is short",
+			items:          []models.OrderItem{{ProductId: "pizza", Quantity: 1, StockReserved: true}, {ProductId: "soup", Quantity: 2, StockReserved: true}},
+			wantOutOfStock: []string{"soup"},
+			wantStock:      map[string]int{"pizza": 3, "soup": 1},
+		},
+		{
+			name:           "unavailable product",
+			items:          []models.OrderItem{{ProductId: "cake", Quantity: 1, StockReserved: true}},
+			wantOutOfStock: []string{"cake"},
+			wantStock:      map[string]int{"pizza": 3, "soup": 1},
+		},
+		{
+			name:      "untracked stock is not reserved",
+			items:     []models.OrderItem{{ProductId: "bread", Quantity: 50}},
+			wantStock: map[string]int{"pizza": 3, "soup": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products := NewProductRepository()
+			orders := NewOrderRepository(products)
+			products.Put(models.Product{Id: "pizza", Available: true, StockQuantity: stock(3)})
+			products.Put(models.Product{Id: "soup", Available: true, StockQuantity: stock(1)})
+			products.Put(models.Product{Id: "cake", Available: false, StockQuantity: stock(10)})
+			products.Put(models.Product{Id: "bread", Available: true})
+
+			order, _, err := orders.Create(models.Order{UserId: customer.ID}, tt.items, customer)
+
+			var outOfStockErr *models.OutOfStockError
+			switch {
+			case tt.wantOutOfStock == nil && err != nil:
+				t.Fatalf("Create() error = %v", err)
+			case tt.wantOutOfStock != nil && !errors.As(err, &outOfStockErr):
+				t.Fatalf("Create() error = %v, want *OutOfStockError", err)
+			case tt.wantOutOfStock != nil && !reflect.DeepEqual(outOfStockErr.Ids, tt.wantOutOfStock):
+				t.Errorf("out of stock = %v, want %v", outOfStockErr.Ids, tt.wantOutOfStock)
+			}
+			if err != nil && order != nil {
+				t.Errorf("Create() stored an order that failed")
+			}
+
+			for productId, want := range tt.wantStock {
+				product, _ := products.GetById(productId)
+				if *product.StockQuantity != want {
+					t.Errorf("%s stock = %d, want %d", productId, *product.StockQuantity, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateExistingOrder(t *testing.T) {
+	products := NewProductRepository()
+	orders := NewOrderRepository(products)
+	products.Put(models.Product{Id: "pizza", Available: true, StockQuantity: stock(3)})
+	items := []models.OrderItem{{ProductId: "pizza", Quantity: 1, StockReserved: true}}
+
+	if _, _, err := orders.Create(models.Order{Id: "order-1", UserId: customer.ID}, items, customer); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	_, _, err := orders.Create(models.Order{Id: "order-1", UserId: customer.ID}, items, customer)
+	if !errors.Is(err, models.ErrOrderAlreadyExists) {
+		t.Fatalf("second Create() error = %v, want %v", err, models.ErrOrderAlreadyExists)
+	}
+	if product, _ := products.GetById("pizza"); *product.StockQuantity != 2 {
+		t.Errorf("stock = %d, want 2: a repeated order must not reserve twice", *product.StockQuantity)
+	}
+}
+
+func TestUpdateStatusTransitions(t *testing.T) {
+	staff := models.Actor{ID: "staff-1", Role: "staff"}
+
+	tests := []struct {
+		name    string
+		steps   []models.OrderStatus
+		wantErr bool
+	}{
+		{"delivered", []models.OrderStatus{models.StatusConfirmed, models.StatusDelivering, models.StatusDelivered}, false},
+		{"canceled while pending", []models.OrderStatus{models.StatusCanceled}, false},
+		{"skips confirmation", []models.OrderStatus{models.StatusDelivering}, true},
+		{"canceled while delivering", []models.OrderStatus{models.StatusConfirmed, models.StatusDelivering, models.StatusCanceled}, true},
+		{"canceled twice", []models.OrderStatus{models.StatusCanceled, models.StatusCanceled}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders := NewOrderRepository(NewProductRepository())
+			order, _, err := orders.Create(models.Order{UserId: customer.ID}, nil, customer)
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			var lastErr error
+			for _, status := range tt.steps {
+				_, lastErr = orders.UpdateStatus(order.Id, status, staff, "", nil)
+				if lastErr != nil {
+					break
+				}
+			}
+
+			var transitionErr *models.InvalidTransitionError
+			if got := errors.As(lastErr, &transitionErr); got != tt.wantErr {
+				t.Fatalf("UpdateStatus() error = %v, wantErr %v", lastErr, tt.wantErr)
+			}
+
+			// Rejected changes leave neither the status nor the history
+			// behind.
+			stored, _ := orders.GetById(order.Id)
+			history, _ := orders.GetStatusHistory(order.Id)
+			last := history[len(history)-1]
+			if last.To != stored.Status {
+				t.Errorf("last history entry = %s, order status = %s", last.To, stored.Status)
+			}
+			if tt.wantErr && stored.Status != transitionErr.From {
+				t.Errorf("status after a rejected change = %s, want %s", stored.Status, transitionErr.From)
+			}
+		})
+	}
+}
@@ -0,0 +1,464 @@
+// Package memory implements the model repositories in memory with the same
+// semantics as their DynamoDB counterparts, including conditional-write
+// failures. It is meant for tests and local development.
+package memory
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/utils"
+	"github.com/google/uuid"
+)
+
+// NewRepositories returns an empty in-memory repository set.
+func NewRepositories() *models.Repositories {
+	products := NewProductRepository()
+	return &models.Repositories{
+		Ads:               NewAdRepository(),
+		Categories:        NewCategoryRepository(),
+		Products:          products,
+		DeliveryAddresses: NewDeliveryAddressRepository(),
+		Orders:            NewOrderRepository(products),
+		Users:             NewUserRepository(),
+		Idempotency:       NewIdempotencyRepository(),
+		Sessions:          NewSessionRepository(),
+	}
+}
+
+// sortedValues returns the values of m ordered by key, so listings are
+// deterministic.
+func sortedValues[T any](m map[string]T) []T {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]T, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, m[key])
+	}
+	return values
+}
+
+// paginate returns one page of items. Its cursors are signed offsets, bound
+// to scope like the DynamoDB ones.
+func paginate[T any](items []T, scope string, request models.PageRequest) (*models.Page[T], error) {
+	offset := 0
+	if request.Cursor != "" {
+		payload, err := utils.VerifyCursor(scope, request.Cursor)
+		if errors.Is(err, utils.ErrInvalidCursor) {
+			return nil, models.ErrInvalidCursor
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		offset, err = strconv.Atoi(string(payload))
+		if err != nil || offset < 0 {
+			return nil, models.ErrInvalidCursor
+		}
+	}
+
+	page := &models.Page[T]{Items: []T{}}
+	if offset >= len(items) {
+		return page, nil
+	}
+
+	end := min(offset+request.PageLimit(), len(items))
+	page.Items = append(page.Items, items[offset:end]...)
+	if end < len(items) {
+		nextCursor, err := utils.SignCursor(scope, []byte(strconv.Itoa(end)))
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = nextCursor
+	}
+	return page, nil
+}
+
+type AdRepository struct {
+	mu  sync.RWMutex
+	ads map[string]models.Ad
+}
+
+func NewAdRepository() *AdRepository {
+	return &AdRepository{ads: map[string]models.Ad{}}
+}
+
+// Put stores ads, replacing any with the same id.
+func (r *AdRepository) Put(ads ...models.Ad) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ad := range ads {
+		r.ads[ad.Id] = ad
+	}
+}
+
+func (r *AdRepository) ListAll(page models.PageRequest) (*models.Page[models.Ad], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return paginate(sortedValues(r.ads), "ads", page)
+}
+
+func (r *AdRepository) GetById(adId string) (*models.Ad, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ad, ok := r.ads[adId]
+	if !ok {
+		return nil, models.ErrAdNotFound
+	}
+	return &ad, nil
+}
+
+func (r *AdRepository) Create(ad models.Ad) (*models.Ad, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ad.Id == "" {
+		ad.Id = uuid.New().String()
+	}
+	r.ads[ad.Id] = ad
+	return &ad, nil
+}
+
+func (r *AdRepository) Update(ad models.Ad) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.ads[ad.Id]; !ok {
+		return models.ErrAdNotFound
+	}
+	r.ads[ad.Id] = ad
+	return nil
+}
+
+func (r *AdRepository) Delete(adId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.ads[adId]; !ok {
+		return models.ErrAdNotFound
+	}
+	delete(r.ads, adId)
+	return nil
+}
+
+type CategoryRepository struct {
+	mu         sync.RWMutex
+	categories map[string]models.Category
+}
+
+func NewCategoryRepository() *CategoryRepository {
+	return &CategoryRepository{categories: map[string]models.Category{}}
+}
+
+// Put stores categories, replacing any with the same id.
+func (r *CategoryRepository) Put(categories ...models.Category) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, category := range categories {
+		r.categories[category.Id] = category
+	}
+}
+
+func (r *CategoryRepository) ListAll(page models.PageRequest) (*models.Page[models.Category], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return paginate(sortedValues(r.categories), "categories", page)
+}
+
+func (r *CategoryRepository) GetById(categoryId string) (*models.Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	category, ok := r.categories[categoryId]
+	if !ok {
+		return nil, models.ErrCategoryNotFound
+	}
+	return &category, nil
+}
+
+func (r *CategoryRepository) Create(category models.Category) (*models.Category, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if category.Id == "" {
+		category.Id = uuid.New().String()
+	}
+	r.categories[category.Id] = category
+	return &category, nil
+}
+
+func (r *CategoryRepository) Update(category models.Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.categories[category.Id]
+	if !ok {
+		return models.ErrCategoryNotFound
+	}
+	category.Sku = stored.Sku
+	r.categories[category.Id] = category
+	return nil
+}
+
+func (r *CategoryRepository) Delete(categoryId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.categories[categoryId]; !ok {
+		return models.ErrCategoryNotFound
+	}
+	delete(r.categories, categoryId)
+	return nil
+}
+
+func (r *CategoryRepository) BatchWrite(put []models.Category, deleteIds []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, category := range put {
+		r.categories[category.Id] = category
+	}
+	for _, categoryId := range deleteIds {
+		delete(r.categories, categoryId)
+	}
+	return nil
+}
+
+type ProductRepository struct {
+	mu       sync.RWMutex
+	products map[string]models.Product
+}
+
+func NewProductRepository() *ProductRepository {
+	return &ProductRepository{products: map[string]models.Product{}}
+}
+
+// Put stores products, replacing any with the same id. Products are only
+// orderable when Available is set.
+func (r *ProductRepository) Put(products ...models.Product) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, product := range products {
+		r.products[product.Id] = product
+	}
+}
+
+func (r *ProductRepository) ListAll(page models.PageRequest) (*models.Page[models.Product], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return paginate(sortedValues(r.products), "products", page)
+}
+
+func (r *ProductRepository) ListByCategory(categoryId string, page models.PageRequest) (*models.Page[models.Product], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var products []models.Product
+	for _, product := range sortedValues(r.products) {
+		if product.CategoryId == categoryId {
+			products = append(products, product)
+		}
+	}
+	return paginate(products, "products#"+categoryId, page)
+}
+
+func (r *ProductRepository) GetById(productId string) (*models.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	product, ok := r.products[productId]
+	if !ok {
+		return nil, models.ErrProductNotFound
+	}
+	return &product, nil
+}
+
+func (r *ProductRepository) GetByIds(productIds []string) (map[string]models.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := map[string]models.Product{}
+	var missing []string
+	for _, productId := range productIds {
+		if _, ok := products[productId]; ok || slices.Contains(missing, productId) {
+			continue
+		}
+		product, ok := r.products[productId]
+		if !ok {
+			missing = append(missing, productId)
+			continue
+		}
+		products[productId] = product
+	}
+
+	if len(missing) > 0 {
+		return products, &models.ProductsNotFoundError{Ids: missing}
+	}
+	return products, nil
+}
+
+func (r *ProductRepository) Create(product models.Product) (*models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if product.Id == "" {
+		product.Id = uuid.New().String()
+	}
+	r.products[product.Id] = product
+	return &product, nil
+}
+
+func (r *ProductRepository) Update(product models.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.products[product.Id]
+	if !ok {
+		return models.ErrProductNotFound
+	}
+	product.Sku = stored.Sku
+	product.Available = stored.Available
+	product.StockQuantity = stored.StockQuantity
+	r.products[product.Id] = product
+	return nil
+}
+
+func (r *ProductRepository) SetAvailability(productId string, available bool, stockQuantity *int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, ok := r.products[productId]
+	if !ok {
+		return models.ErrProductNotFound
+	}
+	product.Available = available
+	product.StockQuantity = nil
+	if stockQuantity != nil {
+		quantity := *stockQuantity
+		product.StockQuantity = &quantity
+	}
+	r.products[productId] = product
+	return nil
+}
+
+// restock returns reserved quantities to the products that still track
+// stock.
+func (r *ProductRepository) restock(reservations []models.StockReservation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, reservation := range reservations {
+		product, ok := r.products[reservation.ProductId]
+		if !ok || product.StockQuantity == nil {
+			continue
+		}
+		stock := *product.StockQuantity + reservation.Quantity
+		product.StockQuantity = &stock
+		r.products[reservation.ProductId] = product
+	}
+}
+
+// reserve takes the quantity of items with StockReserved from their
+// products, or nothing at all when one of them cannot be ordered.
+func (r *ProductRepository) reserve(items []models.OrderItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reservations := models.StockReservations(items)
+	var outOfStock []string
+	for _, reservation := range reservations {
+		product, ok := r.products[reservation.ProductId]
+		if !ok || product.StockQuantity == nil || !product.CanOrder(reservation.Quantity) {
+			outOfStock = append(outOfStock, reservation.ProductId)
+		}
+	}
+	if len(outOfStock) > 0 {
+		sort.Strings(outOfStock)
+		return &models.OutOfStockError{Ids: outOfStock}
+	}
+
+	for _, reservation := range reservations {
+		product := r.products[reservation.ProductId]
+		stock := *product.StockQuantity - reservation.Quantity
+		product.StockQuantity = &stock
+		r.products[reservation.ProductId] = product
+	}
+	return nil
+}
+
+func (r *ProductRepository) Delete(productId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.products[productId]; !ok {
+		return models.ErrProductNotFound
+	}
+	delete(r.products, productId)
+	return nil
+}
+
+func (r *ProductRepository) BatchWrite(put []models.Product, deleteIds []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, product := range put {
+		r.products[product.Id] = product
+	}
+	for _, productId := range deleteIds {
+		delete(r.products, productId)
+	}
+	return nil
+}
+
+type DeliveryAddressRepository struct {
+	mu        sync.RWMutex
+	addresses map[string]models.DeliveryAddress
+}
+
+func NewDeliveryAddressRepository() *DeliveryAddressRepository {
+	return &DeliveryAddressRepository{addresses: map[string]models.DeliveryAddress{}}
+}
+
+func (r *DeliveryAddressRepository) GetById(addressId string) (*models.DeliveryAddress, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	address, ok := r.addresses[addressId]
+	if !ok {
+		return nil, models.ErrDeliveryAddressNotFound
+	}
+	return &address, nil
+}
+
+func (r *DeliveryAddressRepository) Create(address models.DeliveryAddress) (*models.DeliveryAddress, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if address.Id == "" {
+		address.Id = uuid.New().String()
+	}
+	r.addresses[address.Id] = address
+	return &address, nil
+}
+
+func (r *DeliveryAddressRepository) ListByUser(userId string) ([]models.DeliveryAddress, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var addresses []models.DeliveryAddress
+	for _, address := range sortedValues(r.addresses) {
+		if address.UserId == userId {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
+}
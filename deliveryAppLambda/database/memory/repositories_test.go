@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+)
+
+func TestProductRepositoryGetByIds(t *testing.T) {
+	products := NewProductRepository()
+	pizza, err := products.Create(models.Product{Name: "Pizza", Available: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		ids         []string
+		wantFound   []string
+		wantMissing []string
+	}{
+		{"found", []string{pizza.Id}, []string{pizza.Id}, nil},
+		{"same product on two lines", []string{pizza.Id, pizza.Id}, []string{pizza.Id}, nil},
+		{"missing products are reported once", []string{"soup", pizza.Id, "cake", "soup"}, []string{pizza.Id}, []string{"soup", "cake"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := products.GetByIds(tt.ids)
+
+			var missing []string
+			var notFoundErr *models.ProductsNotFoundError
+			if errors.As(err, &notFoundErr) {
+				missing = notFoundErr.Ids
+			} else if err != nil {
+				t.Fatalf("GetByIds() error = %v", err)
+			}
+			if !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("missing = %v, want %v", missing, tt.wantMissing)
+			}
+
+			var foundIds []string
+			for id := range found {
+				foundIds = append(foundIds, id)
+			}
+			if !reflect.DeepEqual(foundIds, tt.wantFound) {
+				t.Errorf("found = %v, want %v", foundIds, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestUpdateKeepsSku(t *testing.T) {
+	categories := NewCategoryRepository()
+	category, err := categories.Create(models.Category{Name: "Pizza", Sku: "CAT-PIZZA"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	products := NewProductRepository()
+	product, err := products.Create(models.Product{Name: "Margherita", Sku: "PIZZA-1", CategoryId: category.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		update  func() error
+		readSku func() (string, error)
+		wantSku string
+	}{
+		{
+			name:   "category",
+			update: func() error { return categories.Update(models.Category{Id: category.Id, Name: "Pizzas"}) },
+			readSku: func() (string, error) {
+				stored, err := categories.GetById(category.Id)
+				if err != nil {
+					return "", err
+				}
+				return stored.Sku, nil
+			},
+			wantSku: "CAT-PIZZA",
+		},
+		{
+			name:   "product",
+			update: func() error { return products.Update(models.Product{Id: product.Id, Name: "Margherita XL"}) },
+			readSku: func() (string, error) {
+				stored, err := products.GetById(product.Id)
+				if err != nil {
+					return "", err
+				}
+				return stored.Sku, nil
+			},
+			wantSku: "PIZZA-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.update(); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			sku, err := tt.readSku()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sku != tt.wantSku {
+				t.Errorf("sku after Update() = %q, want %q", sku, tt.wantSku)
+			}
+		})
+	}
+}
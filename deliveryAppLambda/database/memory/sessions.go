@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+)
+
+type SessionRepository struct {
+	mu       sync.Mutex
+	sessions map[string]models.Session
+}
+
+func NewSessionRepository() *SessionRepository {
+	return &SessionRepository{sessions: map[string]models.Session{}}
+}
+
+func (r *SessionRepository) Create(session models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessions[session.Id] = session
+	return nil
+}
+
+func (r *SessionRepository) Get(sessionId string) (*models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionId]
+	if !ok {
+		return nil, models.ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) Rotate(sessionId, previousHash, tokenHash string, expiresAt int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionId]
+	if !ok {
+		return models.ErrSessionNotFound
+	}
+	if session.TokenHash != previousHash {
+		return models.ErrSessionTokenChanged
+	}
+	session.TokenHash = tokenHash
+	session.ExpiresAt = expiresAt
+	r.sessions[sessionId] = session
+	return nil
+}
+
+func (r *SessionRepository) Delete(sessionId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, sessionId)
+	return nil
+}
+
+func (r *SessionRepository) DeleteByUser(userId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, session := range r.sessions {
+		if session.UserId == userId {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
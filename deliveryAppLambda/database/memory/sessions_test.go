@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+)
+
+func TestSessionRepositoryRotate(t *testing.T) {
+	tests := []struct {
+		name         string
+		sessionId    string
+		previousHash string
+		wantErr      error
+	}{
+		{"current hash", "session-1", "hash-1", nil},
+		{"rotated hash", "session-1", "hash-0", models.ErrSessionTokenChanged},
+		{"ended session", "session-2", "hash-1", models.ErrSessionNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := NewSessionRepository()
+			if err := sessions.Create(models.Session{Id: "session-1", UserId: "user-1", TokenHash: "hash-1"}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := sessions.Rotate(tt.sessionId, tt.previousHash, "hash-2", 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Rotate() error = %v, want %v", err, tt.wantErr)
+			}
+
+			wantHash := "hash-2"
+			if tt.wantErr != nil {
+				wantHash = "hash-1"
+			}
+			if session, _ := sessions.Get("session-1"); session.TokenHash != wantHash {
+				t.Errorf("token hash = %q, want %q", session.TokenHash, wantHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+)
+
+type UserRepository struct {
+	mu    sync.RWMutex
+	users map[string]models.User
+}
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{users: map[string]models.User{}}
+}
+
+func (r *UserRepository) Create(user models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Phone == user.Phone {
+			return models.ErrPhoneAlreadyRegistered
+		}
+	}
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *UserRepository) GetById(userId string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[userId]
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) GetByPhone(phone string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Phone == phone {
+			return &user, nil
+		}
+	}
+	return nil, models.ErrUserNotFound
+}
+
+func (r *UserRepository) UpdateOTP(userId string, state models.OTPState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userId]
+	if !ok {
+		return models.ErrUserNotFound
+	}
+	if user.OTPVersion != state.OTPVersion-1 {
+		return models.ErrOTPStateChanged
+	}
+	user.OTPState = state
+	r.users[userId] = user
+	return nil
+}
+
+func (r *UserRepository) SetRoles(userId string, roles []models.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userId]
+	if !ok {
+		return models.ErrUserNotFound
+	}
+	user.Roles = append([]models.Role(nil), roles...)
+	r.users[userId] = user
+	return nil
+}
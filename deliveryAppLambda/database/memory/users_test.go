@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+)
+
+// The memory repositories must fail the same conditional writes as the
+// DynamoDB ones, or tests against them would miss lost updates.
+
+func TestUserRepositoryCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    models.User
+		wantErr error
+	}{
+		{"new phone", models.User{ID: "user-2", Phone: "+15550101"}, nil},
+		{"registered phone", models.User{ID: "user-2", Phone: "+15550100"}, models.ErrPhoneAlreadyRegistered},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := NewUserRepository()
+			if err := users.Create(models.User{ID: "user-1", Phone: "+15550100"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := users.Create(tt.user); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryUpdateOTP(t *testing.T) {
+	tests := []struct {
+		name    string
+		userId  string
+		version int
+		wantErr error
+	}{
+		{"next version", "user-1", 4, nil},
+		{"stale version", "user-1", 3, models.ErrOTPStateChanged},
+		{"skipped version", "user-1", 5, models.ErrOTPStateChanged},
+		{"unknown user", "user-2", 4, models.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := NewUserRepository()
+			err := users.Create(models.User{ID: "user-1", Phone: "+15550100", OTPState: models.OTPState{OTPVersion: 3, OTPHash: "old"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = users.UpdateOTP(tt.userId, models.OTPState{OTPVersion: tt.version, OTPHash: "new"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateOTP() error = %v, want %v", err, tt.wantErr)
+			}
+
+			wantHash := "new"
+			if tt.wantErr != nil {
+				wantHash = "old"
+			}
+			if user, _ := users.GetById("user-1"); user.OTPHash != wantHash {
+				t.Errorf("OTP hash = %q, want %q", user.OTPHash, wantHash)
+			}
+		})
+	}
+}
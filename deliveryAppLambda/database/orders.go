@@ -0,0 +1,316 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+)
+
+// OrderRepository stores orders, their items and their status history, each
+// in its own table, and reserves the stock of ordered products.
+type OrderRepository struct {
+	client        *dynamodb.Client
+	table         string
+	itemsTable    string
+	historyTable  string
+	productsTable string
+}
+
+// Create stores a new order, its items and the first entry of its status
+// history in a single transaction, so the order either exists completely or
+// not at all. The same transaction takes the quantity of items with
+// StockReserved from their product, on condition that the product is still
+// available and has enough stock.
+func (r *OrderRepository) Create(order models.Order, items []models.OrderItem, actor models.Actor) (*models.Order, []models.OrderItem, error) {
+	if len(items) > models.MaxOrderItems {
+		return nil, nil, models.ErrOrderTooLarge
+	}
+
+	if order.Id == "" {
+		order.Id = uuid.New().String()
+	}
+	
+	if order.Status == "" {
+		order.Status = models.StatusPending
+	}
+	if order.CreatedAt == "" {
+		order.CreatedAt = time.Now().Format(time.RFC3339)
+	}
+
+	item, err := attributevalue.MarshalMap(order)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	historyPut, err := r.statusChangePut(models.NewOrderStatusChange(order.Id, "", order.Status, actor, "order created"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transactItems := []types.TransactWriteItem{
+		{
+			Put: &types.Put{
+				TableName:           &r.table,
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(id)"),
+			},
+		},
+		{Put: historyPut},
+	}
+
+	savedItems := make([]models.OrderItem, 0, len(items))
+	for _, orderItem := range items {
+		if orderItem.Id == "" {
+			orderItem.Id = uuid.New().String()
+		}
+		orderItem.OrderId = order.Id
+
+		itemAttrs, err := attributevalue.MarshalMap(orderItem)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		transactItems = append(transactItems, types.TransactWriteItem{
+			Put: &types.Put{
+				TableName: &r.itemsTable,
+				Item:      itemAttrs,
+			},
+		})
+		savedItems = append(savedItems, orderItem)
+	}
+
+	// reservations maps the index of each stock update in the transaction to
+	// its product.
+	reservations := map[int]string{}
+	for _, reservation := range models.StockReservations(items) {
+		reservations[len(transactItems)] = reservation.ProductId
+		transactItems = append(transactItems, types.TransactWriteItem{
+			Update: r.stockReservation(reservation),
+		})
+	}
+
+	_, err = r.client.TransactWriteItems(context.TODO(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	if err != nil {
+		if isConditionalCheckFailure(err, 0) {
+			return nil, nil, models.ErrOrderAlreadyExists
+		}
+
+		var outOfStock []string
+		for index, productId := range reservations {
+			if isConditionalCheckFailure(err, index) {
+				outOfStock = append(outOfStock, productId)
+			}
+		}
+		if len(outOfStock) > 0 {
+			sort.Strings(outOfStock)
+			return nil, nil, &models.OutOfStockError{Ids: outOfStock}
+		}
+		return nil, nil, err
+	}
+
+	return &order, savedItems, nil
+}
+
+// stockReservation builds the transaction entry that takes the reserved
+// quantity from a product's stock.
+func (r *OrderRepository) stockReservation(reservation models.StockReservation) *types.Update {
+	return &types.Update{
+		TableName:           &r.productsTable,
+		Key:                 idKey(reservation.ProductId),
+		UpdateExpression:    aws.String("SET stockQuantity = stockQuantity - :quantity"),
+		ConditionExpression: aws.String("(attribute_not_exists(available) OR available = :true) AND stockQuantity >= :quantity"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":quantity": &types.AttributeValueMemberN{Value: strconv.Itoa(reservation.Quantity)},
+			":true":     &types.AttributeValueMemberBOOL{Value: true},
+		},
+	}
+}
+
+// stockReturn builds the transaction entry that gives a reservation back to
+// a product, on condition that the product still tracks stock.
+func (r *OrderRepository) stockReturn(reservation models.StockReservation) *types.Update {
+	return &types.Update{
+		TableName:           &r.productsTable,
+		Key:                 idKey(reservation.ProductId),
+		UpdateExpression:    aws.String("SET stockQuantity = stockQuantity + :quantity"),
+		ConditionExpression: aws.String("attribute_exists(stockQuantity)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":quantity": &types.AttributeValueMemberN{Value: strconv.Itoa(reservation.Quantity)},
+		},
+	}
+}
+
+func (r *OrderRepository) GetById(orderId string) (*models.Order, error) {
+	result, err := r.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: &r.table,
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: orderId},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, models.ErrOrderNotFound
+	}
+
+	var order models.Order
+	err = attributevalue.UnmarshalMap(result.Item, &order)
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// UpdateStatus moves an order to status if the transition is allowed and
+// appends the change to the order's status history in the same transaction,
+// along with the updates that return restock to the products. The write is
+// conditioned on the status that was read, so of two concurrent updates only
+// one can succeed; the other gets an *InvalidTransitionError. Stock is only
+// returned to products that still track it: when the transaction is canceled
+// because a product was deleted or stopped tracking stock, it is retried
+// without that product.
+func (r *OrderRepository) UpdateStatus(orderId string, status models.OrderStatus, actor models.Actor, reason string, restock []models.StockReservation) (*models.Order, error) {
+	order, err := r.GetById(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !order.Status.CanTransitionTo(status) {
+		return nil, &models.InvalidTransitionError{From: order.Status, To: status}
+	}
+
+	historyPut, err := r.statusChangePut(models.NewOrderStatusChange(orderId, order.Status, status, actor, reason))
+	if err != nil {
+		return nil, err
+	}
+
+	statusUpdate := &types.Update{
+		TableName: &r.table,
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: orderId},
+		},
+		UpdateExpression:    aws.String("SET #status = :status"),
+		ConditionExpression: aws.String("#status = :currentStatus"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status":        &types.AttributeValueMemberS{Value: string(status)},
+			":currentStatus": &types.AttributeValueMemberS{Value: string(order.Status)},
+		},
+	}
+
+	for {
+		transactItems := []types.TransactWriteItem{
+			{Update: statusUpdate},
+			{Put: historyPut},
+		}
+		for _, reservation := range restock {
+			transactItems = append(transactItems, types.TransactWriteItem{
+				Update: r.stockReturn(reservation),
+			})
+		}
+
+		_, err = r.client.TransactWriteItems(context.TODO(), &dynamodb.TransactWriteItemsInput{
+			TransactItems: transactItems,
+		})
+		if err == nil {
+			break
+		}
+		if isConditionalCheckFailure(err, 0) {
+			from := order.Status
+			if latest, getErr := r.GetById(orderId); getErr == nil {
+				from = latest.Status
+			}
+			return nil, &models.InvalidTransitionError{From: from, To: status}
+		}
+
+		var tracked []models.StockReservation
+		for i, reservation := range restock {
+			if !isConditionalCheckFailure(err, 2+i) {
+				tracked = append(tracked, reservation)
+			}
+		}
+		if len(tracked) == len(restock) {
+			return nil, err
+		}
+		restock = tracked
+	}
+
+	order.Status = status
+	return order, nil
+}
+
+// ListByUser returns one page of the orders of a user, newest first.
+func (r *OrderRepository) ListByUser(userId string, page models.PageRequest) (*models.Page[models.Order], error) {
+	return queryPage[models.Order](r.client, &dynamodb.QueryInput{
+		TableName:              &r.table,
+		IndexName:              aws.String(userOrdersIndex),
+		KeyConditionExpression: aws.String("userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: userId},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}, "orders#"+userId, page)
+}
+
+func (r *OrderRepository) GetItems(orderId string) ([]models.OrderItem, error) {
+	return queryAll[models.OrderItem](r.client, &dynamodb.QueryInput{
+		TableName:              &r.itemsTable,
+		IndexName:              aws.String(orderItemsIndex),
+		KeyConditionExpression: aws.String("orderId = :orderId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":orderId": &types.AttributeValueMemberS{Value: orderId},
+		},
+	})
+}
+
+// GetStatusHistory returns the status changes of an order, oldest first.
+func (r *OrderRepository) GetStatusHistory(orderId string) ([]models.OrderStatusChange, error) {
+	return queryAll[models.OrderStatusChange](r.client, &dynamodb.QueryInput{
+		TableName:              &r.historyTable,
+		KeyConditionExpression: aws.String("orderId = :orderId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":orderId": &types.AttributeValueMemberS{Value: orderId},
+		},
+	})
+}
+
+// statusChangePut builds the transaction entry that appends change to the
+// history table without ever overwriting an existing entry.
+func (r *OrderRepository) statusChangePut(change models.OrderStatusChange) (*types.Put, error) {
+	item, err := attributevalue.MarshalMap(change)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Put{
+		TableName:           &r.historyTable,
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(sortKey)"),
+	}, nil
+}
+
+// isConditionalCheckFailure reports whether a transaction was canceled because
+// the condition of the item at index failed.
+func isConditionalCheckFailure(err error, index int) bool {
+	var canceledErr *types.TransactionCanceledException
+	if !errors.As(err, &canceledErr) || index >= len(canceledErr.CancellationReasons) {
+		return false
+	}
+	return aws.ToString(canceledErr.CancellationReasons[index].Code) == "ConditionalCheckFailed"
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// cursorAttribute is the JSON form of a key attribute. Table and index keys
+// are only ever strings or numbers.
+type cursorAttribute struct {
+	S *string `json:"S,omitempty"`
+	N *string `json:"N,omitempty"`
+}
+
+// encodeCursor turns a LastEvaluatedKey into a signed cursor bound to scope.
+func encodeCursor(scope string, key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+
+	attributes := make(map[string]cursorAttribute, len(key))
+	for name, value := range key {
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			attributes[name] = cursorAttribute{S: aws.String(v.Value)}
+		case *types.AttributeValueMemberN:
+			attributes[name] = cursorAttribute{N: aws.String(v.Value)}
+		default:
+			return "", fmt.Errorf("unsupported key attribute type %T for %s", value, name)
+		}
+	}
+
+	payload, err := json.Marshal(attributes)
+	if err != nil {
+		return "", err
+	}
+	return utils.SignCursor(scope, payload)
+}
+
+// decodeCursor verifies a cursor issued by encodeCursor for the same scope
+// and returns the ExclusiveStartKey it carries.
+func decodeCursor(scope, cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	payload, err := utils.VerifyCursor(scope, cursor)
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		return nil, models.ErrInvalidCursor
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var attributes map[string]cursorAttribute
+	if err := json.Unmarshal(payload, &attributes); err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+
+	key := make(map[string]types.AttributeValue, len(attributes))
+	for name, attribute := range attributes {
+		switch {
+		case attribute.S != nil:
+			key[name] = &types.AttributeValueMemberS{Value: *attribute.S}
+		case attribute.N != nil:
+			key[name] = &types.AttributeValueMemberN{Value: *attribute.N}
+		default:
+			return nil, models.ErrInvalidCursor
+		}
+	}
+	return key, nil
+}
+
+// queryPage runs one page of a query starting at the request's cursor. The
+// filter-free queries used here read exactly Limit items per page, so the
+// page may be short only at the end of the results.
+func queryPage[T any](client *dynamodb.Client, input *dynamodb.QueryInput, scope string, request models.PageRequest) (*models.Page[T], error) {
+	startKey, err := decodeCursor(scope, request.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	input.ExclusiveStartKey = startKey
+	input.Limit = aws.Int32(int32(request.PageLimit()))
+	output, err := client.Query(context.TODO(), input)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPage[T](scope, output.Items, output.LastEvaluatedKey)
+}
+
+// scanPage is queryPage for full-table listings.
+func scanPage[T any](client *dynamodb.Client, input *dynamodb.ScanInput, scope string, request models.PageRequest) (*models.Page[T], error) {
+	startKey, err := decodeCursor(scope, request.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	input.ExclusiveStartKey = startKey
+	input.Limit = aws.Int32(int32(request.PageLimit()))
+	output, err := client.Scan(context.TODO(), input)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPage[T](scope, output.Items, output.LastEvaluatedKey)
+}
+
+func newPage[T any](scope string, items []map[string]types.AttributeValue, lastKey map[string]types.AttributeValue) (*models.Page[T], error) {
+	page := &models.Page[T]{Items: []T{}}
+	if err := attributevalue.UnmarshalListOfMaps(items, &page.Items); err != nil {
+		return nil, err
+	}
+
+	nextCursor, err := encodeCursor(scope, lastKey)
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = nextCursor
+	return page, nil
+}
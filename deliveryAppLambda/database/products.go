@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+)
+
+// batchGetMaxKeys is the largest number of keys BatchGetItem accepts.
+const batchGetMaxKeys = 100
+
+// maxBatchAttempts bounds the retries of unprocessed keys and items.
+const maxBatchAttempts = 5
+
+type ProductRepository struct {
+	client *dynamodb.Client
+	table  string
+}
+
+func (r *ProductRepository) ListAll(page models.PageRequest) (*models.Page[models.Product], error) {
+	return scanPage[models.Product](r.client, &dynamodb.ScanInput{
+		TableName: &r.table,
+	}, "products", page)
+}
+
+func (r *ProductRepository) ListByCategory(categoryId string, page models.PageRequest) (*models.Page[models.Product], error) {
+	return queryPage[models.Product](r.client, &dynamodb.QueryInput{
+		TableName:              &r.table,
+		IndexName:              aws.String(categoryProductsIndex),
+		KeyConditionExpression: aws.String("categoryId = :categoryId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":categoryId": &types.AttributeValueMemberS{Value: categoryId},
+		},
+	}, "products#"+categoryId, page)
+}
+
+func (r *ProductRepository) GetById(productId string) (*models.Product, error) {
+	result, err := r.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: &r.table,
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: productId},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, models.ErrProductNotFound
+	}
+
+	var product models.Product
+	err = attributevalue.UnmarshalMap(result.Item, &product)
+	if err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// GetByIds fetches products with BatchGetItem, retrying unprocessed keys with
+// backoff. Products missing from the table are reported together in a
+// *models.ProductsNotFoundError.
+func (r *ProductRepository) GetByIds(productIds []string) (map[string]models.Product, error) {
+	var keys []map[string]types.AttributeValue
+	var uniqueIds []string
+	seen := map[string]bool{}
+	for _, productId := range productIds {
+		if seen[productId] {
+			continue
+		}
+		seen[productId] = true
+		uniqueIds = append(uniqueIds, productId)
+		keys = append(keys, map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: productId},
+		})
+	}
+
+	products := make(map[string]models.Product, len(keys))
+	for start := 0; start < len(keys); start += batchGetMaxKeys {
+		end := min(start+batchGetMaxKeys, len(keys))
+		requestItems := map[string]types.KeysAndAttributes{
+			r.table: {Keys: keys[start:end]},
+		}
+
+		for attempt := 0; len(requestItems) > 0; attempt++ {
+			if attempt == maxBatchAttempts {
+				return nil, fmt.Errorf("failed to get products: keys still unprocessed after %d attempts", attempt)
+			}
+			if attempt > 0 {
+				time.Sleep(time.Duration(1<<attempt) * 50 * time.Millisecond)
+			}
+
+			result, err := r.client.BatchGetItem(context.TODO(), &dynamodb.BatchGetItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			var page []models.Product
+			err = attributevalue.UnmarshalListOfMaps(result.Responses[r.table], &page)
+			if err != nil {
+				return nil, err
+			}
+			for _, product := range page {
+				products[product.Id] = product
+			}
+
+			requestItems = result.UnprocessedKeys
+		}
+	}
+
+	var missing []string
+	for _, productId := range uniqueIds {
+		if _, ok := products[productId]; !ok {
+			missing = append(missing, productId)
+		}
+	}
+	if len(missing) > 0 {
+		return products, &models.ProductsNotFoundError{Ids: missing}
+	}
+
+	return products, nil
+}
+
+func (r *ProductRepository) Create(product models.Product) (*models.Product, error) {
+	if product.Id == "" {
+		product.Id = uuid.New().String()
+	}
+
+	err := putItem(r.client, r.table, product)
+	if err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// Update sets the catalog fields of a product with UpdateItem, so it never
+// overwrites stock that orders reserve concurrently, nor the SKU.
+func (r *ProductRepository) Update(product models.Product) error {
+	update := expression.Set(expression.Name("name"), expression.Value(product.Name)).
+		Set(expression.Name("description"), expression.Value(product.Description)).
+		Set(expression.Name("price"), expression.Value(product.Price)).
+		Set(expression.Name("imageUrl"), expression.Value(product.ImageUrl)).
+		Set(expression.Name("categoryId"), expression.Value(product.CategoryId))
+	if len(product.VariantGroups) > 0 {
+		update = update.Set(expression.Name("variantGroups"), expression.Value(product.VariantGroups))
+	} else {
+		update = update.Remove(expression.Name("variantGroups"))
+	}
+	if len(product.ModifierGroups) > 0 {
+		update = update.Set(expression.Name("modifierGroups"), expression.Value(product.ModifierGroups))
+	} else {
+		update = update.Remove(expression.Name("modifierGroups"))
+	}
+
+	return updateItem(r.client, r.table, product.Id, update, models.ErrProductNotFound)
+}
+
+func (r *ProductRepository) SetAvailability(productId string, available bool, stockQuantity *int) error {
+	update := expression.Set(expression.Name("available"), expression.Value(available))
+	if stockQuantity != nil {
+		update = update.Set(expression.Name("stockQuantity"), expression.Value(*stockQuantity))
+	} else {
+		update = update.Remove(expression.Name("stockQuantity"))
+	}
+
+	return updateItem(r.client, r.table, productId, update, models.ErrProductNotFound)
+}
+
+func (r *ProductRepository) Delete(productId string) error {
+	return deleteItem(r.client, r.table, productId, models.ErrProductNotFound)
+}
+
+func (r *ProductRepository) BatchWrite(put []models.Product, deleteIds []string) error {
+	return batchPutDelete(r.client, r.table, put, deleteIds)
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Global secondary indexes created by the CDK stack.
+const (
+	userOrdersIndex          = "UserOrdersIndex"
+	userDeliveryAddressIndex = "UserIndex"
+	orderItemsIndex          = "OrderIndex"
+	categoryProductsIndex    = "CategoryIndex"
+	userSessionsIndex        = "UserIndex"
+)
+
+// queryAll runs a query through every page of results, so callers never get
+// a list silently truncated at the 1 MB page limit.
+func queryAll[T any](client *dynamodb.Client, input *dynamodb.QueryInput) ([]T, error) {
+	var items []T
+	paginator := dynamodb.NewQueryPaginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		var pageItems []T
+		err = attributevalue.UnmarshalListOfMaps(page.Items, &pageItems)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, pageItems...)
+	}
+	return items, nil
+}
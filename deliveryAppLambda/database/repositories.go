@@ -0,0 +1,37 @@
+package database
+
+import (
+	"github.com/ZED-Magdy/delivery-cdk/lambda/clients"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// NewRepositories builds the DynamoDB repositories on the shared client, using
+// the table names from the environment.
+func NewRepositories() (*models.Repositories, error) {
+	c, err := clients.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRepositoriesWithClient(c.DynamoDB, GetTables()), nil
+}
+
+func NewRepositoriesWithClient(client *dynamodb.Client, tables Tables) *models.Repositories {
+	return &models.Repositories{
+		Ads:               &AdRepository{client: client, table: tables.AdsTable},
+		Categories:        &CategoryRepository{client: client, table: tables.CategoriesTable},
+		Products:          &ProductRepository{client: client, table: tables.ProductsTable},
+		DeliveryAddresses: &DeliveryAddressRepository{client: client, table: tables.DeliveryAddressTable},
+		Orders: &OrderRepository{
+			client:        client,
+			table:         tables.OrdersTable,
+			itemsTable:    tables.OrderItemsTable,
+			historyTable:  tables.OrderStatusHistoryTable,
+			productsTable: tables.ProductsTable,
+		},
+		Users:       &UserRepository{client: client, table: tables.UsersTable},
+		Idempotency: &IdempotencyRepository{client: client, table: tables.IdempotencyTable},
+		Sessions:    &SessionRepository{client: client, table: tables.SessionsTable},
+	}
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type SessionRepository struct {
+	client *dynamodb.Client
+	table  string
+}
+
+func (r *SessionRepository) Create(session models.Session) error {
+	item, err := attributevalue.MarshalMap(session)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: &r.table,
+		Item:      item,
+	})
+	return err
+}
+
+// Get reads consistently, so a rotated token is never compared against the
+// hash it replaced.
+func (r *SessionRepository) Get(sessionId string) (*models.Session, error) {
+	result, err := r.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: &r.table,
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: sessionId},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, models.ErrSessionNotFound
+	}
+
+	var session models.Session
+	err = attributevalue.UnmarshalMap(result.Item, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (r *SessionRepository) Rotate(sessionId, previousHash, tokenHash string, expiresAt int64) error {
+	_, err := r.client.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: &r.table,
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: sessionId},
+		},
+		UpdateExpression:    aws.String("SET tokenHash = :tokenHash, expiresAt = :expiresAt"),
+		ConditionExpression: aws.String("tokenHash = :previousHash"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":tokenHash":    &types.AttributeValueMemberS{Value: tokenHash},
+			":previousHash": &types.AttributeValueMemberS{Value: previousHash},
+			":expiresAt":    &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt, 10)},
+		},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		var conditionalCheckFailedErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalCheckFailedErr) {
+			if len(conditionalCheckFailedErr.Item) == 0 {
+				return models.ErrSessionNotFound
+			}
+			return models.ErrSessionTokenChanged
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *SessionRepository) Delete(sessionId string) error {
+	_, err := r.client.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName: &r.table,
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: sessionId},
+		},
+	})
+	return err
+}
+
+// DeleteByUser finds the user's sessions through the UserIndex GSI and deletes
+// them in batches.
+func (r *SessionRepository) DeleteByUser(userId string) error {
+	sessions, err := queryAll[models.Session](r.client, &dynamodb.QueryInput{
+		TableName:              &r.table,
+		IndexName:              aws.String(userSessionsIndex),
+		KeyConditionExpression: aws.String("userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: userId},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var requests []types.WriteRequest
+	for _, session := range sessions {
+		requests = append(requests, types.WriteRequest{
+			DeleteRequest: &types.DeleteRequest{
+				Key: map[string]types.AttributeValue{
+					"id": &types.AttributeValueMemberS{Value: session.Id},
+				},
+			},
+		})
+	}
+
+	return batchWrite(r.client, r.table, requests)
+}
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type UserRepository struct {
+	client *dynamodb.Client
+	table  string
+}
+
+// Create stores a new user unless the phone number is already registered.
+func (r *UserRepository) Create(user models.User) error {
+	_, err := r.GetByPhone(user.Phone)
+	if err == nil {
+		return models.ErrPhoneAlreadyRegistered
+	}
+	if !errors.Is(err, models.ErrUserNotFound) {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(user)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: &r.table,
+		Item:      item,
+		ConditionExpression: aws.String("attribute_not_exists(phone)"),
+	})
+	
+	if err != nil {
+		var conditionalCheckFailedErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalCheckFailedErr) {
+			return models.ErrPhoneAlreadyRegistered
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetById reads consistently, so the OTP state it returns is current.
+func (r *UserRepository) GetById(userId string) (*models.User, error) {
+	response, err := r.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: &r.table,
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: userId},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Item) == 0 {
+		return nil, models.ErrUserNotFound
+	}
+
+	user := new(models.User)
+	err = attributevalue.UnmarshalMap(response.Item, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) GetByPhone(phone string) (*models.User, error) {
+	keyEx := expression.Key("phone").Equal(expression.Value(phone))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.client.Query(context.TODO(), &dynamodb.QueryInput{
+		TableName:              &r.table,
+		IndexName:              aws.String("PhoneIndex"),
+		KeyConditionExpression: expr.KeyCondition(),
+		ExpressionAttributeNames: expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var users []models.User
+	err = attributevalue.UnmarshalListOfMaps(response.Items, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		return nil, models.ErrUserNotFound
+	}
+
+	return &users[0], nil
+}
+
+// UpdateOTP writes the OTP state of a user, guarded by its version, and drops
+// the plaintext otp attribute written by earlier versions.
+func (r *UserRepository) UpdateOTP(userId string, state models.OTPState) error {
+	attributes, err := attributevalue.MarshalMap(state)
+	if err != nil {
+		return err
+	}
+
+	var updateExpr expression.UpdateBuilder
+	for name, value := range attributes {
+		updateExpr = updateExpr.Set(expression.Name(name), expression.Value(value))
+	}
+	updateExpr = updateExpr.Remove(expression.Name("otp"))
+
+	previousVersion := expression.Value(state.OTPVersion - 1)
+	versionCondition := expression.Name("otp_version").Equal(previousVersion)
+	if state.OTPVersion-1 == 0 {
+		// Users created before OTP state was versioned have no version.
+		versionCondition = expression.AttributeNotExists(expression.Name("otp_version")).Or(versionCondition)
+	}
+	condition := expression.AttributeExists(expression.Name("id")).And(versionCondition)
+
+	expr, err := expression.NewBuilder().WithUpdate(updateExpr).WithCondition(condition).Build()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: &r.table,
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: userId},
+		},
+		UpdateExpression:                    expr.Update(),
+		ConditionExpression:                 expr.Condition(),
+		ExpressionAttributeNames:            expr.Names(),
+		ExpressionAttributeValues:           expr.Values(),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		var conditionalCheckFailedErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalCheckFailedErr) {
+			if len(conditionalCheckFailedErr.Item) == 0 {
+				return models.ErrUserNotFound
+			}
+			return models.ErrOTPStateChanged
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *UserRepository) SetRoles(userId string, roles []models.Role) error {
+	expr, err := expression.NewBuilder().
+		WithUpdate(expression.Set(expression.Name("roles"), expression.Value(roles))).
+		WithCondition(expression.AttributeExists(expression.Name("id"))).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: &r.table,
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: userId},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if err != nil {
+		var conditionalCheckFailedErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalCheckFailedErr) {
+			return models.ErrUserNotFound
+		}
+		return err
+	}
+
+	return nil
+}
@@ -2,20 +2,26 @@ package handlers
 
 import (
 	"encoding/json"
+	"net/http"
 
 	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
 	"github.com/aws/aws-lambda-go/events"
 )
 
-func GetAds(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	ads, err := models.ListAll()
+func (h *Handler) GetAds(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	page, err := parsePageRequest(request)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
-			StatusCode: 500,
+			StatusCode: 400,
 			Body:       err.Error(),
 		}, nil
 	}
 
+	ads, err := h.Ads.ListAll(page)
+	if err != nil {
+		return listErrorResponse(err), nil
+	}
+
 	jsonBody, err := json.Marshal(ads)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
@@ -28,4 +34,69 @@ func GetAds(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespon
 		StatusCode: 200,
 		Body:       string(jsonBody),
 	}, nil
-}
\ No newline at end of file
+}
+
+func (h *Handler) CreateAd(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var input AdRequest
+	if errResponse := parseCatalogRequest(request, &input); errResponse != nil {
+		return *errResponse, nil
+	}
+
+	fieldErrors, err := h.checkAdAction(&input)
+	if err != nil {
+		return catalogErrorResponse("creating ad", err), nil
+	}
+	if len(fieldErrors) > 0 {
+		return validationErrorResponse(fieldErrors), nil
+	}
+
+	ad, err := h.Ads.Create(models.Ad{
+		ImageUrl:   input.ImageUrl,
+		Action:     input.Action,
+		ActionType: input.ActionType,
+	})
+	if err != nil {
+		return catalogErrorResponse("creating ad", err), nil
+	}
+
+	return catalogResponse(http.StatusCreated, ad), nil
+}
+
+func (h *Handler) UpdateAd(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var input AdRequest
+	if errResponse := parseCatalogRequest(request, &input); errResponse != nil {
+		return *errResponse, nil
+	}
+
+	fieldErrors, err := h.checkAdAction(&input)
+	if err != nil {
+		return catalogErrorResponse("updating ad", err), nil
+	}
+	if len(fieldErrors) > 0 {
+		return validationErrorResponse(fieldErrors), nil
+	}
+
+	ad := models.Ad{
+		Id:         request.PathParameters["adId"],
+		ImageUrl:   input.ImageUrl,
+		Action:     input.Action,
+		ActionType: input.ActionType,
+	}
+	err = h.Ads.Update(ad)
+	if err != nil {
+		return catalogErrorResponse("updating ad", err), nil
+	}
+
+	return catalogResponse(http.StatusOK, ad), nil
+}
+
+func (h *Handler) DeleteAd(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	err := h.Ads.Delete(request.PathParameters["adId"])
+	if err != nil {
+		return catalogErrorResponse("deleting ad", err), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNoContent,
+	}, nil
+}
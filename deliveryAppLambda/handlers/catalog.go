@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// The admin catalog endpoints create, update and delete categories,
+// products and ads. Updates take the same body as creates. An ad is replaced
+// whole, while a category or product is read first and keeps its SKU, and a
+// product its availability and stock, which have their own endpoint.
+
+type CategoryRequest struct {
+	Name     string `json:"name"`
+	ImageUrl string `json:"imageUrl"`
+}
+
+type ProductRequest struct {
+	Name           string                 `json:"name"`
+	Description    string                 `json:"description"`
+	Price          *models.Money          `json:"price"`
+	ImageUrl       string                 `json:"imageUrl"`
+	CategoryId     string                 `json:"categoryId"`
+	VariantGroups  []models.VariantGroup  `json:"variantGroups"`
+	ModifierGroups []models.ModifierGroup `json:"modifierGroups"`
+}
+
+type ProductAvailabilityRequest struct {
+	Available *bool `json:"available"`
+	// StockQuantity is the stock left, or null for products that are not
+	// stock-tracked.
+	StockQuantity *int `json:"stockQuantity"`
+}
+
+type AdRequest struct {
+	ImageUrl   string `json:"imageUrl"`
+	Action     string `json:"action"`
+	ActionType string `json:"actionType"`
+}
+
+// parseCatalogRequest decodes and validates the body of a catalog write.
+func parseCatalogRequest[T interface{ Validate() []FieldError }](request events.APIGatewayProxyRequest, input T) *events.APIGatewayProxyResponse {
+	if err := json.Unmarshal([]byte(request.Body), input); err != nil {
+		return &events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request format: " + err.Error(),
+		}
+	}
+
+	if fieldErrors := input.Validate(); len(fieldErrors) > 0 {
+		response := validationErrorResponse(fieldErrors)
+		return &response
+	}
+
+	return nil
+}
+
+// checkCategory reports a categoryId field error when the category does not
+// exist.
+func (h *Handler) checkCategory(categoryId string) ([]FieldError, error) {
+	_, err := h.Categories.GetById(categoryId)
+	if errors.Is(err, models.ErrCategoryNotFound) {
+		return []FieldError{{Field: "categoryId", Message: "category not found"}}, nil
+	}
+	return nil, err
+}
+
+// checkAdAction reports an action field error when the product or category an
+// ad opens does not exist.
+func (h *Handler) checkAdAction(input *AdRequest) ([]FieldError, error) {
+	var err error
+	switch input.ActionType {
+	case models.AdActionProduct:
+		_, err = h.Products.GetById(input.Action)
+	case models.AdActionCategory:
+		_, err = h.Categories.GetById(input.Action)
+	}
+
+	if errors.Is(err, models.ErrProductNotFound) || errors.Is(err, models.ErrCategoryNotFound) {
+		return []FieldError{{Field: "action", Message: input.ActionType + " not found"}}, nil
+	}
+	return nil, err
+}
+
+func catalogResponse(statusCode int, item interface{}) events.APIGatewayProxyResponse {
+	jsonBody, err := json.Marshal(item)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error converting response to JSON",
+		}
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Body:       string(jsonBody),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+}
+
+// catalogErrorResponse maps the not-found errors of the catalog repositories
+// to 404 and anything else to 500.
+func catalogErrorResponse(action string, err error) events.APIGatewayProxyResponse {
+	switch {
+	case errors.Is(err, models.ErrCategoryNotFound):
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNotFound, Body: "Category not found"}
+	case errors.Is(err, models.ErrProductNotFound):
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNotFound, Body: "Product not found"}
+	case errors.Is(err, models.ErrAdNotFound):
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNotFound, Body: "Ad not found"}
+	case errors.Is(err, models.ErrCategoryNotEmpty):
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusConflict, Body: "Category still has products; move or delete them first"}
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusInternalServerError,
+		Body:       "Error " + action + ": " + err.Error(),
+	}
+}
+
+type ImportCatalogResponse struct {
+	DryRun bool `json:"dryRun"`
+	models.CatalogDiff
+}
+
+// catalogFormat reads the format query parameter, defaulting to JSON.
+func catalogFormat(request events.APIGatewayProxyRequest) (string, *events.APIGatewayProxyResponse) {
+	format := strings.ToLower(request.QueryStringParameters["format"])
+	switch format {
+	case "":
+		return models.CatalogFormatJSON, nil
+	case models.CatalogFormatJSON, models.CatalogFormatCSV:
+		return format, nil
+	}
+
+	return "", &events.APIGatewayProxyResponse{
+		StatusCode: http.StatusBadRequest,
+		Body:       models.ErrUnknownCatalogFormat.Error(),
+	}
+}
+
+// ImportCatalog replaces the catalog with the uploaded CSV or JSON file.
+// With dryRun=true it only reports how many items would be created, updated
+// and deleted.
+func (h *Handler) ImportCatalog(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	format, errResponse := catalogFormat(request)
+	if errResponse != nil {
+		return *errResponse, nil
+	}
+
+	dryRun := false
+	if value := request.QueryStringParameters["dryRun"]; value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "dryRun must be true or false",
+			}, nil
+		}
+		dryRun = parsed
+	}
+
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "Invalid request body encoding",
+			}, nil
+		}
+		body = decoded
+	}
+
+	catalog, err := models.DecodeCatalog(bytes.NewReader(body), format)
+	if err != nil {
+		if response, ok := catalogValidationResponse(err); ok {
+			return response, nil
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	plan, err := models.PlanCatalogImport(h.Categories, h.Products, catalog)
+	if err != nil {
+		if response, ok := catalogValidationResponse(err); ok {
+			return response, nil
+		}
+		return catalogErrorResponse("importing catalog", err), nil
+	}
+
+	if !dryRun {
+		err = plan.Apply(h.Categories, h.Products)
+		if err != nil {
+			return catalogErrorResponse("importing catalog", err), nil
+		}
+	}
+
+	return catalogResponse(http.StatusOK, ImportCatalogResponse{
+		DryRun:      dryRun,
+		CatalogDiff: plan.Diff,
+	}), nil
+}
+
+// catalogValidationResponse maps a *models.CatalogValidationError to a 422
+// response listing every problem.
+func catalogValidationResponse(err error) (events.APIGatewayProxyResponse, bool) {
+	var validationErr *models.CatalogValidationError
+	if !errors.As(err, &validationErr) {
+		return events.APIGatewayProxyResponse{}, false
+	}
+
+	return catalogResponse(http.StatusUnprocessableEntity, map[string]interface{}{
+		"message":  "Invalid catalog",
+		"problems": validationErr.Problems,
+	}), true
+}
+
+// ExportCatalog returns the whole catalog as a CSV or JSON file.
+func (h *Handler) ExportCatalog(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	format, errResponse := catalogFormat(request)
+	if errResponse != nil {
+		return *errResponse, nil
+	}
+
+	catalog, err := models.ExportCatalog(h.Categories, h.Products)
+	if err != nil {
+		return catalogErrorResponse("exporting catalog", err), nil
+	}
+
+	var body bytes.Buffer
+	err = models.EncodeCatalog(&body, format, catalog)
+	if err != nil {
+		return catalogErrorResponse("exporting catalog", err), nil
+	}
+
+	contentType := "application/json"
+	if format == models.CatalogFormatCSV {
+		contentType = "text/csv"
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       body.String(),
+		Headers: map[string]string{
+			"Content-Type":        contentType,
+			"Content-Disposition": "attachment; filename=catalog." + format,
+		},
+	}, nil
+}
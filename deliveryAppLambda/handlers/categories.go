@@ -2,20 +2,26 @@ package handlers
 
 import (
 	"encoding/json"
+	"net/http"
 
 	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
 	"github.com/aws/aws-lambda-go/events"
 )
 
-func GetCategories(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	categories, err := models.ListAllCategories()
+func (h *Handler) GetCategories(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	page, err := parsePageRequest(request)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
-			StatusCode: 500,
+			StatusCode: 400,
 			Body:       err.Error(),
 		}, nil
 	}
 
+	categories, err := h.Categories.ListAll(page)
+	if err != nil {
+		return listErrorResponse(err), nil
+	}
+
 	jsonBody, err := json.Marshal(categories)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
@@ -29,3 +35,54 @@ func GetCategories(request events.APIGatewayProxyRequest) (events.APIGatewayProx
 		Body:       string(jsonBody),
 	}, nil
 }
+
+func (h *Handler) CreateCategory(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var input CategoryRequest
+	if errResponse := parseCatalogRequest(request, &input); errResponse != nil {
+		return *errResponse, nil
+	}
+
+	category, err := h.Categories.Create(models.Category{
+		Name:     input.Name,
+		ImageUrl: input.ImageUrl,
+	})
+	if err != nil {
+		return catalogErrorResponse("creating category", err), nil
+	}
+
+	return catalogResponse(http.StatusCreated, category), nil
+}
+
+func (h *Handler) UpdateCategory(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var input CategoryRequest
+	if errResponse := parseCatalogRequest(request, &input); errResponse != nil {
+		return *errResponse, nil
+	}
+
+	category, err := h.Categories.GetById(request.PathParameters["categoryId"])
+	if err != nil {
+		return catalogErrorResponse("updating category", err), nil
+	}
+
+	category.Name = input.Name
+	category.ImageUrl = input.ImageUrl
+	err = h.Categories.Update(*category)
+	if err != nil {
+		return catalogErrorResponse("updating category", err), nil
+	}
+
+	return catalogResponse(http.StatusOK, category), nil
+}
+
+// DeleteCategory deletes an empty category and answers 409 while it still
+// has products.
+func (h *Handler) DeleteCategory(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	err := models.DeleteCategory(h.Categories, h.Products, request.PathParameters["categoryId"])
+	if err != nil {
+		return catalogErrorResponse("deleting category", err), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNoContent,
+	}, nil
+}
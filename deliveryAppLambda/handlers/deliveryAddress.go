@@ -15,8 +15,8 @@ type CreateDeliveryAddressRequest struct {
 	Longitude   float64 `json:"longitude,omitempty"`
 }
 
-func CreateDeliveryAddress(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	user, err := models.GetAuthUser(request)
+func (h *Handler) CreateDeliveryAddress(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	principal, err := models.RequestPrincipal(request)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 401,
@@ -24,7 +24,7 @@ func CreateDeliveryAddress(request events.APIGatewayProxyRequest) (events.APIGat
 		}, nil
 	}
 
-	userId := user.ID
+	userId := principal.UserID
 
 	var createReq CreateDeliveryAddressRequest
 	err = json.Unmarshal([]byte(request.Body), &createReq)
@@ -42,7 +42,7 @@ func CreateDeliveryAddress(request events.APIGatewayProxyRequest) (events.APIGat
 		}, nil
 	}
 
-	address, err := models.CreateDeliveryAddress(models.DeliveryAddress{
+	address, err := h.DeliveryAddresses.Create(models.DeliveryAddress{
 		UserId:      userId,
 		Name:        createReq.Name,
 		AddressLine: createReq.AddressLine,
@@ -70,8 +70,8 @@ func CreateDeliveryAddress(request events.APIGatewayProxyRequest) (events.APIGat
 	}, nil
 }
 
-func GetUserDeliveryAddresses(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	user, err := models.GetAuthUser(request)
+func (h *Handler) GetUserDeliveryAddresses(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	principal, err := models.RequestPrincipal(request)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 401,
@@ -79,9 +79,9 @@ func GetUserDeliveryAddresses(request events.APIGatewayProxyRequest) (events.API
 		}, nil
 	}
 
-	userId := user.ID
+	userId := principal.UserID
 
-	addresses, err := models.GetUserDeliveryAddresses(userId)
+	addresses, err := h.DeliveryAddresses.ListByUser(userId)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 500,
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/services"
+)
+
+// Handler serves the API routes and queue events. Its dependencies are set
+// once per Lambda container, or replaced with in-memory ones in tests.
+type Handler struct {
+	models.Repositories
+	Queue     services.OrderQueue
+	Notifier  *services.Notifier
+	OTPSender models.OTPSender
+}
+
+// NewHandler wires a Handler to the given repositories, order queue and OTP
+// sender.
+func NewHandler(repos *models.Repositories, queue services.OrderQueue, otpSender models.OTPSender) *Handler {
+	return &Handler{
+		Repositories: *repos,
+		Queue:        queue,
+		OTPSender:    otpSender,
+		Notifier: &services.Notifier{
+			Orders: repos.Orders,
+			Users:  repos.Users,
+		},
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/utils"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// GetJWKS publishes the public keys that verify our access tokens.
+func (h *Handler) GetJWKS(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	jwks, err := utils.JWKS()
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error loading signing keys",
+		}, nil
+	}
+
+	jsonBody, err := json.Marshal(jwks)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error converting response to JSON",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(jsonBody),
+		Headers: map[string]string{
+			"Content-Type":  "application/json",
+			"Cache-Control": "public, max-age=300",
+		},
+	}, nil
+}
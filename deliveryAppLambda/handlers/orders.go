@@ -2,10 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
-	"github.com/ZED-Magdy/delivery-cdk/lambda/services"
 	"github.com/aws/aws-lambda-go/events"
 )
 
@@ -14,18 +14,30 @@ type CreateOrderRequest struct {
 	Items             []OrderItemRequest  `json:"items"`
 }
 
+// OrderItemRequest is a line of a new order. VariantIds picks one variant of
+// each variant group of the product and ModifierIds its add-ons.
 type OrderItemRequest struct {
-	ProductId string `json:"productId"`
-	Quantity  int    `json:"quantity"`
+	ProductId   string   `json:"productId"`
+	Quantity    int      `json:"quantity"`
+	VariantIds  []string `json:"variantIds,omitempty"`
+	ModifierIds []string `json:"modifierIds,omitempty"`
+
+	// index is the position of the line in the request, set by Validate.
+	index int
+}
+
+type CancelOrderRequest struct {
+	Reason string `json:"reason"`
 }
 
 type OrderResponse struct {
-	Order models.Order       `json:"order"`
-	Items []models.OrderItem `json:"items,omitempty"`
+	Order   models.Order               `json:"order"`
+	Items   []models.OrderItem         `json:"items,omitempty"`
+	History []models.OrderStatusChange `json:"history,omitempty"`
 }
 
-func CreateOrder(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	user, err := models.GetAuthUser(request)
+func (h *Handler) CreateOrder(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	principal, err := models.RequestPrincipal(request)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 401,
@@ -33,7 +45,7 @@ func CreateOrder(request events.APIGatewayProxyRequest) (events.APIGatewayProxyR
 		}, nil
 	}
 
-	userId := user.ID
+	userId := principal.UserID
 
 	var createReq CreateOrderRequest
 	err = json.Unmarshal([]byte(request.Body), &createReq)
@@ -44,7 +56,11 @@ func CreateOrder(request events.APIGatewayProxyRequest) (events.APIGatewayProxyR
 		}, nil
 	}
 
-	address, err := models.GetDeliveryAddressById(createReq.DeliveryAddressId)
+	if fieldErrors := createReq.Validate(); len(fieldErrors) > 0 {
+		return validationErrorResponse(fieldErrors), nil
+	}
+
+	address, err := h.DeliveryAddresses.GetById(createReq.DeliveryAddressId)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 422,
@@ -59,58 +75,131 @@ func CreateOrder(request events.APIGatewayProxyRequest) (events.APIGatewayProxyR
 		}, nil
 	}
 
-	var total float64
+	productIds := make([]string, 0, len(createReq.Items))
+	for _, itemReq := range createReq.Items {
+		productIds = append(productIds, itemReq.ProductId)
+	}
+
+	products, err := h.Products.GetByIds(productIds)
+	if err != nil {
+		var notFoundErr *models.ProductsNotFoundError
+		if errors.As(err, &notFoundErr) {
+			var fieldErrors []FieldError
+			for _, productId := range notFoundErr.Ids {
+				fieldErrors = append(fieldErrors, FieldError{
+					Field:   fmt.Sprintf("items[%d].productId", indexOfProduct(createReq.Items, productId)),
+					Message: fmt.Sprintf("product %s not found", productId),
+				})
+			}
+			return validationErrorResponse(fieldErrors), nil
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       "Error retrieving products: " + err.Error(),
+		}, nil
+	}
+
+	var fieldErrors []FieldError
+	quantities := map[string]int{}
+	for _, itemReq := range createReq.Items {
+		quantities[itemReq.ProductId] += itemReq.Quantity
+	}
+	for _, itemReq := range createReq.Items {
+		product := products[itemReq.ProductId]
+		index := indexOfProduct(createReq.Items, product.Id)
+		if index == itemReq.index && !product.CanOrder(quantities[product.Id]) {
+			fieldErrors = append(fieldErrors, outOfStockFieldError(index, product))
+		}
+	}
+
+	var total models.Money
 	var orderItems []models.OrderItem
 	
 	for _, itemReq := range createReq.Items {
-		product, err := models.GetProductById(itemReq.ProductId)
-		if (err != nil) {
+		product := products[itemReq.ProductId]
+
+		options, unitPrice, err := product.SelectOptions(itemReq.VariantIds, itemReq.ModifierIds)
+		var selectionErr *models.OptionSelectionError
+		if errors.As(err, &selectionErr) {
+			for _, problem := range selectionErr.Problems {
+				fieldErrors = append(fieldErrors, FieldError{
+					Field:   fmt.Sprintf("items[%d]", itemReq.index),
+					Message: problem,
+				})
+			}
+			continue
+		}
+		if err != nil {
 			return events.APIGatewayProxyResponse{
-				StatusCode: 422,
-				Body:       fmt.Sprintf("Invalid product ID %s: %s", itemReq.ProductId, err.Error()),
+				StatusCode: 500,
+				Body:       "Error pricing product options: " + err.Error(),
 			}, nil
 		}
 		
-		itemTotal := product.Price * float64(itemReq.Quantity)
-		total += itemTotal
+		lineTotal, err := unitPrice.Multiply(itemReq.Quantity)
+		if err == nil {
+			total, err = total.Add(lineTotal)
+		}
+		if errors.Is(err, models.ErrMoneyOutOfRange) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 422,
+				Body:       "Order total is too large: " + err.Error(),
+			}, nil
+		}
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 422,
+				Body:       "All products in an order must have the same currency: " + err.Error(),
+			}, nil
+		}
 		
 		orderItems = append(orderItems, models.OrderItem{
-			ProductId: product.Id,
-			Name:      product.Name,
-			Price:     product.Price,
-			Quantity:  itemReq.Quantity,
+			ProductId:     product.Id,
+			Name:          product.Name,
+			Price:         unitPrice,
+			Quantity:      itemReq.Quantity,
+			Options:       options,
+			StockReserved: product.StockQuantity != nil,
 		})
 	}
+	if len(fieldErrors) > 0 {
+		return validationErrorResponse(fieldErrors), nil
+	}
 
-	order, err := models.CreateOrder(models.Order{
+	order, savedOrderItems, err := h.Orders.Create(models.Order{
 		UserId:            userId,
 		Total:             total,
 		Status:            models.StatusPending,
 		DeliveryAddressId: createReq.DeliveryAddressId,
-	})
+	}, orderItems, models.Actor{ID: userId, Role: models.ActorRoleCustomer})
 	if err != nil {
+		if errors.Is(err, models.ErrOrderTooLarge) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 422,
+				Body:       err.Error(),
+			}, nil
+		}
+		// Stock ran out between the checks above and the transaction
+		var outOfStockErr *models.OutOfStockError
+		if errors.As(err, &outOfStockErr) {
+			var fieldErrors []FieldError
+			for _, productId := range outOfStockErr.Ids {
+				fieldErrors = append(fieldErrors, FieldError{
+					Field:   fmt.Sprintf("items[%d].quantity", indexOfProduct(createReq.Items, productId)),
+					Message: fmt.Sprintf("product %s is out of stock", productId),
+				})
+			}
+			return validationErrorResponse(fieldErrors), nil
+		}
 		return events.APIGatewayProxyResponse{
 			StatusCode: 500,
 			Body:       "Error creating order: " + err.Error(),
 		}, nil
 	}
 
-	var savedOrderItems []models.OrderItem
-	for _, item := range orderItems {
-		item.OrderId = order.Id
-		savedItem, err := models.CreateOrderItem(item)
-		if err != nil {
-			return events.APIGatewayProxyResponse{
-				StatusCode: 500,
-				Body:       "Error creating order item: " + err.Error(),
-			}, nil
-		}
-		savedOrderItems = append(savedOrderItems, *savedItem)
-	}
-
 	// After the order is created successfully
 	// Add this after the order and order items are saved successfully
-	err = services.SendOrderToQueue(order.Id, string(models.StatusPending), userId)
+	err = h.Queue.SendOrder(order.Id, string(models.StatusPending), userId)
 	if err != nil {
 		// Log the error but don't fail the order creation
 		fmt.Printf("Error sending order to queue: %v\n", err)
@@ -135,8 +224,8 @@ func CreateOrder(request events.APIGatewayProxyRequest) (events.APIGatewayProxyR
 	}, nil
 }
 
-func CancelOrder(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	user, err := models.GetAuthUser(request)
+func (h *Handler) CancelOrder(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	principal, err := models.RequestPrincipal(request)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 401,
@@ -144,7 +233,7 @@ func CancelOrder(request events.APIGatewayProxyRequest) (events.APIGatewayProxyR
 		}, nil
 	}
 
-	userId := user.ID
+	userId := principal.UserID
 
 	orderId := request.PathParameters["orderId"]
 	if orderId == "" {
@@ -154,7 +243,7 @@ func CancelOrder(request events.APIGatewayProxyRequest) (events.APIGatewayProxyR
 		}, nil
 	}
 
-	order, err := models.GetOrderById(orderId)
+	order, err := h.Orders.GetById(orderId)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 404,
@@ -169,16 +258,47 @@ func CancelOrder(request events.APIGatewayProxyRequest) (events.APIGatewayProxyR
 		}, nil
 	}
 
-	updatedOrder, err := models.UpdateOrderStatus(orderId, models.StatusCanceled)
+	var cancelReq CancelOrderRequest
+	if request.Body != "" {
+		err = json.Unmarshal([]byte(request.Body), &cancelReq)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 400,
+				Body:       "Invalid request format: " + err.Error(),
+			}, nil
+		}
+	}
+	if cancelReq.Reason == "" {
+		cancelReq.Reason = "canceled by customer"
+	}
+
+	items, err := h.Orders.GetItems(orderId)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
-			StatusCode: 400,
-			Body:       err.Error(),
+			StatusCode: 500,
+			Body:       "Error retrieving order items: " + err.Error(),
+		}, nil
+	}
+
+	// The stock the order reserved is returned in the same transaction as
+	// the cancellation
+	updatedOrder, err := h.Orders.UpdateStatus(orderId, models.StatusCanceled, models.Actor{ID: userId, Role: models.ActorRoleCustomer}, cancelReq.Reason, models.StockReservations(items))
+	if err != nil {
+		var transitionErr *models.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 409,
+				Body:       err.Error(),
+			}, nil
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       "Error canceling order: " + err.Error(),
 		}, nil
 	}
 
 	// Send the updated order status to the queue for processing
-	err = services.SendOrderToQueue(orderId, string(models.StatusCanceled), userId)
+	err = h.Queue.SendOrder(orderId, string(models.StatusCanceled), userId)
 	if err != nil {
 		// Log the error but don't fail the cancel operation
 		fmt.Printf("Error sending canceled order to queue: %v\n", err)
@@ -198,8 +318,8 @@ func CancelOrder(request events.APIGatewayProxyRequest) (events.APIGatewayProxyR
 	}, nil
 }
 
-func GetUserOrders(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	user, err := models.GetAuthUser(request)
+func (h *Handler) GetUserOrders(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	principal, err := models.RequestPrincipal(request)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 401,
@@ -207,9 +327,20 @@ func GetUserOrders(request events.APIGatewayProxyRequest) (events.APIGatewayProx
 		}, nil
 	}
 
-	userId := user.ID
+	userId := principal.UserID
 
-	orders, err := models.GetUserOrders(userId)
+	page, err := parsePageRequest(request)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	orders, err := h.Orders.ListByUser(userId, page)
+	if errors.Is(err, models.ErrInvalidCursor) {
+		return listErrorResponse(err), nil
+	}
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 500,
@@ -217,13 +348,16 @@ func GetUserOrders(request events.APIGatewayProxyRequest) (events.APIGatewayProx
 		}, nil
 	}
 
-	var orderResponses []OrderResponse
-	for _, order := range orders {
+	orderResponses := models.Page[OrderResponse]{
+		Items:      []OrderResponse{},
+		NextCursor: orders.NextCursor,
+	}
+	for _, order := range orders.Items {
 		orderCopy := order
 		response := OrderResponse{
 			Order: orderCopy,
 		}
-		orderResponses = append(orderResponses, response)
+		orderResponses.Items = append(orderResponses.Items, response)
 	}
 
 	jsonBody, err := json.Marshal(orderResponses)
@@ -240,8 +374,8 @@ func GetUserOrders(request events.APIGatewayProxyRequest) (events.APIGatewayProx
 	}, nil
 }
 
-func GetOrderDetails(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	user, err := models.GetAuthUser(request)
+func (h *Handler) GetOrderDetails(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	principal, err := models.RequestPrincipal(request)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 401,
@@ -249,7 +383,7 @@ func GetOrderDetails(request events.APIGatewayProxyRequest) (events.APIGatewayPr
 		}, nil
 	}
 
-	userId := user.ID
+	userId := principal.UserID
 
 	orderId := request.PathParameters["orderId"]
 	if orderId == "" {
@@ -259,7 +393,7 @@ func GetOrderDetails(request events.APIGatewayProxyRequest) (events.APIGatewayPr
 		}, nil
 	}
 
-	order, err := models.GetOrderById(orderId)
+	order, err := h.Orders.GetById(orderId)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 404,
@@ -274,7 +408,7 @@ func GetOrderDetails(request events.APIGatewayProxyRequest) (events.APIGatewayPr
 		}, nil
 	}
 
-	items, err := models.GetOrderItems(orderId)
+	items, err := h.Orders.GetItems(orderId)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 500,
@@ -282,9 +416,18 @@ func GetOrderDetails(request events.APIGatewayProxyRequest) (events.APIGatewayPr
 		}, nil
 	}
 
+	history, err := h.Orders.GetStatusHistory(orderId)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       "Error retrieving order history: " + err.Error(),
+		}, nil
+	}
+
 	response := OrderResponse{
-		Order: *order,
-		Items: items,
+		Order:   *order,
+		Items:   items,
+		History: history,
 	}
 
 	jsonBody, err := json.Marshal(response)
@@ -300,3 +443,57 @@ func GetOrderDetails(request events.APIGatewayProxyRequest) (events.APIGatewayPr
 		Body:       string(jsonBody),
 	}, nil
 }
+
+func (h *Handler) GetOrderHistory(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	principal, err := models.RequestPrincipal(request)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Body:       "Unauthorized: " + err.Error(),
+		}, nil
+	}
+
+	orderId := request.PathParameters["orderId"]
+	if orderId == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Body:       "Order ID is required",
+		}, nil
+	}
+
+	order, err := h.Orders.GetById(orderId)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 404,
+			Body:       "Order not found: " + err.Error(),
+		}, nil
+	}
+
+	if order.UserId != principal.UserID {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 403,
+			Body:       "You can only view your own orders",
+		}, nil
+	}
+
+	history, err := h.Orders.GetStatusHistory(orderId)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       "Error retrieving order history: " + err.Error(),
+		}, nil
+	}
+
+	jsonBody, err := json.Marshal(history)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       "Error converting response to JSON",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Body:       string(jsonBody),
+	}, nil
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// parsePageRequest reads the limit and cursor query parameters. Limits above
+// models.MaxPageLimit are clamped rather than rejected.
+func parsePageRequest(request events.APIGatewayProxyRequest) (models.PageRequest, error) {
+	page := models.PageRequest{Cursor: request.QueryStringParameters["cursor"]}
+
+	if limit, ok := request.QueryStringParameters["limit"]; ok {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 {
+			return page, errors.New("limit must be a positive integer")
+		}
+		page.Limit = value
+	}
+
+	return page, nil
+}
+
+// listErrorResponse maps a paginated listing error to a response.
+func listErrorResponse(err error) events.APIGatewayProxyResponse {
+	if errors.Is(err, models.ErrInvalidCursor) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400,
+			Body:       "Invalid cursor",
+		}
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 500,
+		Body:       err.Error(),
+	}
+}
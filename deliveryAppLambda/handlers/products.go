@@ -2,12 +2,41 @@ package handlers
 
 import (
 	"encoding/json"
+	"net/http"
 
 	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
 	"github.com/aws/aws-lambda-go/events"
 )
 
-func GetProducts(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+// ProductResponse is a product as listed to customers. Available tells
+// whether it can be ordered, without revealing how much stock is left.
+type ProductResponse struct {
+	Id             string                 `json:"id"`
+	Name           string                 `json:"name"`
+	Description    string                 `json:"description"`
+	Price          models.Money           `json:"price"`
+	ImageUrl       string                 `json:"imageUrl"`
+	CategoryId     string                 `json:"categoryId"`
+	Available      bool                   `json:"available"`
+	VariantGroups  []models.VariantGroup  `json:"variantGroups,omitempty"`
+	ModifierGroups []models.ModifierGroup `json:"modifierGroups,omitempty"`
+}
+
+func newProductResponse(product models.Product) ProductResponse {
+	return ProductResponse{
+		Id:             product.Id,
+		Name:           product.Name,
+		Description:    product.Description,
+		Price:          product.Price,
+		ImageUrl:       product.ImageUrl,
+		CategoryId:     product.CategoryId,
+		Available:      product.CanOrder(1),
+		VariantGroups:  product.VariantGroups,
+		ModifierGroups: product.ModifierGroups,
+	}
+}
+
+func (h *Handler) GetProducts(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	categoryId, ok := request.PathParameters["categoryId"]
 	
 	if !ok {
@@ -17,15 +46,29 @@ func GetProducts(request events.APIGatewayProxyRequest) (events.APIGatewayProxyR
 		}, nil
 	}
 
-	products, err := models.ListAllProducts(string(categoryId))
+	page, err := parsePageRequest(request)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
-			StatusCode: 500,
+			StatusCode: 400,
 			Body:       err.Error(),
 		}, nil
 	}
 
-	jsonBody, err := json.Marshal(products)
+	products, err := h.Products.ListByCategory(string(categoryId), page)
+	if err != nil {
+		return listErrorResponse(err), nil
+	}
+
+	// Sold out products are listed as unavailable
+	response := models.Page[ProductResponse]{
+		Items:      make([]ProductResponse, 0, len(products.Items)),
+		NextCursor: products.NextCursor,
+	}
+	for _, product := range products.Items {
+		response.Items = append(response.Items, newProductResponse(product))
+	}
+
+	jsonBody, err := json.Marshal(response)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 500,
@@ -38,3 +81,103 @@ func GetProducts(request events.APIGatewayProxyRequest) (events.APIGatewayProxyR
 		Body:       string(jsonBody),
 	}, nil
 }
+
+func (h *Handler) CreateProduct(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var input ProductRequest
+	if errResponse := parseCatalogRequest(request, &input); errResponse != nil {
+		return *errResponse, nil
+	}
+
+	fieldErrors, err := h.checkCategory(input.CategoryId)
+	if err != nil {
+		return catalogErrorResponse("creating product", err), nil
+	}
+	if len(fieldErrors) > 0 {
+		return validationErrorResponse(fieldErrors), nil
+	}
+
+	product, err := h.Products.Create(input.product(""))
+	if err != nil {
+		return catalogErrorResponse("creating product", err), nil
+	}
+
+	return catalogResponse(http.StatusCreated, product), nil
+}
+
+func (h *Handler) UpdateProduct(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var input ProductRequest
+	if errResponse := parseCatalogRequest(request, &input); errResponse != nil {
+		return *errResponse, nil
+	}
+
+	fieldErrors, err := h.checkCategory(input.CategoryId)
+	if err != nil {
+		return catalogErrorResponse("updating product", err), nil
+	}
+	if len(fieldErrors) > 0 {
+		return validationErrorResponse(fieldErrors), nil
+	}
+
+	existing, err := h.Products.GetById(request.PathParameters["productId"])
+	if err != nil {
+		return catalogErrorResponse("updating product", err), nil
+	}
+
+	product := input.product(existing.Id)
+	err = h.Products.Update(product)
+	if err != nil {
+		return catalogErrorResponse("updating product", err), nil
+	}
+
+	product.Sku = existing.Sku
+	product.Available = existing.Available
+	product.StockQuantity = existing.StockQuantity
+	return catalogResponse(http.StatusOK, product), nil
+}
+
+// SetProductAvailability marks a product available or unavailable and sets
+// or clears its stock.
+func (h *Handler) SetProductAvailability(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var input ProductAvailabilityRequest
+	if errResponse := parseCatalogRequest(request, &input); errResponse != nil {
+		return *errResponse, nil
+	}
+
+	productId := request.PathParameters["productId"]
+	err := h.Products.SetAvailability(productId, *input.Available, input.StockQuantity)
+	if err != nil {
+		return catalogErrorResponse("updating availability", err), nil
+	}
+
+	product, err := h.Products.GetById(productId)
+	if err != nil {
+		return catalogErrorResponse("updating availability", err), nil
+	}
+
+	return catalogResponse(http.StatusOK, product), nil
+}
+
+func (h *Handler) DeleteProduct(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	err := h.Products.Delete(request.PathParameters["productId"])
+	if err != nil {
+		return catalogErrorResponse("deleting product", err), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNoContent,
+	}, nil
+}
+
+func (r *ProductRequest) product(productId string) models.Product {
+	return models.Product{
+		Id:             productId,
+		Name:           r.Name,
+		Description:    r.Description,
+		Price:          *r.Price,
+		ImageUrl:       r.ImageUrl,
+		CategoryId:     r.CategoryId,
+		Available:      true,
+		VariantGroups:  r.VariantGroups,
+		ModifierGroups: r.ModifierGroups,
+	}
+}
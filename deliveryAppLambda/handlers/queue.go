@@ -1,22 +1,57 @@
 package handlers
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+
 	"github.com/ZED-Magdy/delivery-cdk/lambda/services"
 	"github.com/aws/aws-lambda-go/events"
 )
 
-func ProcessOrderQueue(request events.SQSEvent) error {
+// ProcessOrderQueue processes a batch of order messages and reports the ones
+// that failed so that only those are retried instead of the whole batch.
+// A message failing its last allowed attempt is moved to the dead-letter
+// queue together with its failure reason.
+func (h *Handler) ProcessOrderQueue(request events.SQSEvent) (events.SQSEventResponse, error) {
+	var response events.SQSEventResponse
+
 	for _, record := range request.Records {
-		// Process each SQS message
-		// The services.ProcessOrdersFromQueue function would typically handle the logic
-		// But for direct SQS Lambda triggers, we can process here directly
-		err := services.ProcessOrderFromMessage(record.Body)
-		if err != nil {
-			// Log the error but continue processing other messages
-			// This prevents the entire batch from failing due to one message
-			// You might want to implement a dead-letter queue for failed messages
-			// in a production system
+		err := h.Notifier.ProcessOrderFromMessage(record.Body)
+		if err == nil {
+			continue
 		}
+
+		fmt.Printf("Failed to process message %s: %v\n", record.MessageId, err)
+
+		if isLastAttempt(record) {
+			dlqErr := services.SendOrderToDeadLetterQueue(record.Body, err.Error())
+			if dlqErr == nil {
+				continue
+			}
+			fmt.Printf("Failed to dead-letter message %s: %v\n", record.MessageId, dlqErr)
+		}
+
+		response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{
+			ItemIdentifier: record.MessageId,
+		})
 	}
-	return nil
+
+	return response, nil
+}
+
+// isLastAttempt reports whether the queue's redrive policy would dead-letter
+// the record if it failed again.
+func isLastAttempt(record events.SQSMessage) bool {
+	maxReceiveCount, err := strconv.Atoi(os.Getenv("ORDER_QUEUE_MAX_RECEIVE_COUNT"))
+	if err != nil || maxReceiveCount <= 0 {
+		return false
+	}
+
+	receiveCount, err := strconv.Atoi(record.Attributes["ApproximateReceiveCount"])
+	if err != nil {
+		return false
+	}
+
+	return receiveCount >= maxReceiveCount
 }
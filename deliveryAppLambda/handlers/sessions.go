@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/utils"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func parseRefreshTokenRequest(request events.APIGatewayProxyRequest) (*RefreshTokenRequest, *events.APIGatewayProxyResponse) {
+	var input RefreshTokenRequest
+	if err := json.Unmarshal([]byte(request.Body), &input); err != nil {
+		return nil, &events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request format",
+		}
+	}
+
+	if input.RefreshToken == "" {
+		return nil, &events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Refresh token is required",
+		}
+	}
+
+	return &input, nil
+}
+
+// RefreshToken exchanges a refresh token for a new access token and the next
+// refresh token of the session.
+func (h *Handler) RefreshToken(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	input, errResponse := parseRefreshTokenRequest(request)
+	if errResponse != nil {
+		return *errResponse, nil
+	}
+
+	session, refreshToken, err := models.RefreshSession(h.Sessions, input.RefreshToken)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		message := "Error refreshing token: " + err.Error()
+
+		switch {
+		case errors.Is(err, models.ErrInvalidRefreshToken):
+			statusCode = http.StatusUnauthorized
+			message = "Invalid refresh token"
+		case errors.Is(err, models.ErrRefreshTokenReused):
+			statusCode = http.StatusUnauthorized
+			message = "Refresh token was already used; the session has been ended"
+		}
+
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Body:       message,
+		}, nil
+	}
+
+	user, err := h.Users.GetById(session.UserId)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Invalid refresh token",
+		}, nil
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error refreshing token: " + err.Error(),
+		}, nil
+	}
+
+	token, err := utils.GenerateJWT(user.ID, user.Name, user.Phone, user.RoleNames())
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error generating token",
+		}, nil
+	}
+
+	type Response struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refreshToken"`
+		ExpiresIn    int    `json:"expiresIn"`
+	}
+
+	jsonResponse, err := json.Marshal(Response{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+	})
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error converting response to JSON",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(jsonResponse),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
+
+// Logout ends the session of the given refresh token. Access tokens already
+// issued stay valid until they expire.
+func (h *Handler) Logout(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	input, errResponse := parseRefreshTokenRequest(request)
+	if errResponse != nil {
+		return *errResponse, nil
+	}
+
+	err := models.EndSession(h.Sessions, input.RefreshToken)
+	if errors.Is(err, models.ErrInvalidRefreshToken) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Invalid refresh token",
+		}, nil
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error logging out: " + err.Error(),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNoContent,
+	}, nil
+}
+
+// LogoutAll ends every session of the authenticated user.
+func (h *Handler) LogoutAll(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	principal, err := models.RequestPrincipal(request)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized: " + err.Error(),
+		}, nil
+	}
+
+	err = models.EndAllSessions(h.Sessions, principal.UserID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error logging out: " + err.Error(),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNoContent,
+	}, nil
+}
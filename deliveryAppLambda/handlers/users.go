@@ -2,14 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
 	"github.com/ZED-Magdy/delivery-cdk/lambda/utils"
 	"github.com/aws/aws-lambda-go/events"
 )
 
-func RegisterUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+func (h *Handler) RegisterUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	var input models.UserRegistrationInput
 	if err := json.Unmarshal([]byte(request.Body), &input); err != nil {
 		return events.APIGatewayProxyResponse{
@@ -25,9 +28,9 @@ func RegisterUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxy
 		}, nil
 	}
 
-	_, err := models.RegisterUser(input)
+	_, err := models.RegisterUser(h.Users, h.OTPSender, input)
 	if err != nil {
-		if err.Error() == "phone number already registered" {
+		if errors.Is(err, models.ErrPhoneAlreadyRegistered) {
 			return events.APIGatewayProxyResponse{
 				StatusCode: http.StatusConflict,
 				Body:       "Phone number already registered",
@@ -60,7 +63,7 @@ func RegisterUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxy
 	}, nil
 }
 
-func VerifyOTP(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+func (h *Handler) VerifyOTP(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	var input models.OTPVerificationInput
 	if err := json.Unmarshal([]byte(request.Body), &input); err != nil {
 		return events.APIGatewayProxyResponse{
@@ -76,19 +79,22 @@ func VerifyOTP(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRes
 		}, nil
 	}
 
-	user, err := models.VerifyOTP(input)
+	user, err := models.VerifyOTP(h.Users, input)
+	if response, limited := otpRateLimitResponse(err); limited {
+		return response, nil
+	}
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		message := "Error verifying OTP: " + err.Error()
 
-		switch err.Error() {
-		case "user not found":
+		switch {
+		case errors.Is(err, models.ErrUserNotFound):
 			statusCode = http.StatusNotFound
 			message = "User not found"
-		case "invalid OTP":
+		case errors.Is(err, models.ErrInvalidOTP):
 			statusCode = http.StatusUnauthorized
 			message = "Invalid OTP"
-		case "OTP expired":
+		case errors.Is(err, models.ErrOTPExpired):
 			statusCode = http.StatusUnauthorized
 			message = "OTP has expired"
 		}
@@ -100,7 +106,7 @@ func VerifyOTP(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRes
 	}
 
 	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID, user.Name, user.Phone)
+	token, err := utils.GenerateJWT(user.ID, user.Name, user.Phone, user.RoleNames())
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: http.StatusInternalServerError,
@@ -108,14 +114,26 @@ func VerifyOTP(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRes
 		}, nil
 	}
 
+	refreshToken, err := models.StartSession(h.Sessions, user.ID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error starting session",
+		}, nil
+	}
+
 	type Response struct {
-		User  *models.User `json:"user"`
-		Token string       `json:"token"`
+		User         *models.User `json:"user"`
+		Token        string       `json:"token"`
+		RefreshToken string       `json:"refreshToken"`
+		ExpiresIn    int          `json:"expiresIn"`
 	}
 
 	response := Response{
-		User:  user,
-		Token: token,
+		User:         user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
 	}
 
 	jsonResponse, err := json.Marshal(response)
@@ -135,7 +153,7 @@ func VerifyOTP(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRes
 	}, nil
 }
 
-func SendOTP(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+func (h *Handler) SendOTP(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	var input models.SendOTPInput
 	if err := json.Unmarshal([]byte(request.Body), &input); err != nil {
 		return events.APIGatewayProxyResponse{
@@ -151,12 +169,15 @@ func SendOTP(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 		}, nil
 	}
 
-	err := models.SendOTP(input)
+	err := models.SendOTP(h.Users, h.OTPSender, input)
+	if response, limited := otpRateLimitResponse(err); limited {
+		return response, nil
+	}
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		message := "Error sending OTP: " + err.Error()
 
-		if err.Error() == "user not found" {
+		if errors.Is(err, models.ErrUserNotFound) {
 			statusCode = http.StatusNotFound
 			message = "User not found with the provided phone number"
 		}
@@ -187,3 +208,84 @@ func SendOTP(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 		},
 	}, nil
 }
+
+// otpRateLimitResponse maps an *models.OTPRateLimitError to a 429 response
+// with a Retry-After header in whole seconds.
+func otpRateLimitResponse(err error) (events.APIGatewayProxyResponse, bool) {
+	var rateLimitErr *models.OTPRateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		return events.APIGatewayProxyResponse{}, false
+	}
+
+	retryAfter := int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusTooManyRequests,
+		Body:       "Too many requests: " + rateLimitErr.Reason,
+		Headers: map[string]string{
+			"Retry-After": strconv.Itoa(retryAfter),
+		},
+	}, true
+}
+
+type SetUserRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// SetUserRoles replaces the roles of a user. Changes reach the user's access
+// token on its next refresh.
+func (h *Handler) SetUserRoles(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	userId := request.PathParameters["userId"]
+	if userId == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "User ID is required",
+		}, nil
+	}
+
+	var input SetUserRolesRequest
+	if err := json.Unmarshal([]byte(request.Body), &input); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request format",
+		}, nil
+	}
+
+	roles, err := models.SetUserRoles(h.Users, userId, input.Roles)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrUnknownRole), errors.Is(err, models.ErrNoRoles):
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       err.Error(),
+			}, nil
+		case errors.Is(err, models.ErrUserNotFound):
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "User not found",
+			}, nil
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error updating roles: " + err.Error(),
+		}, nil
+	}
+
+	jsonResponse, err := json.Marshal(map[string]interface{}{
+		"userId": userId,
+		"roles":  roles,
+	})
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error converting response to JSON",
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(jsonResponse),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
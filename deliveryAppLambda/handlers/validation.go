@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// MaxItemQuantity is the largest quantity of a single product per order.
+const MaxItemQuantity = 100
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ValidationErrorResponse struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+// validationErrorResponse builds the 422 response listing every invalid field.
+func validationErrorResponse(fieldErrors []FieldError) events.APIGatewayProxyResponse {
+	jsonBody, err := json.Marshal(ValidationErrorResponse{
+		Message: "Validation failed",
+		Errors:  fieldErrors,
+	})
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error converting response to JSON",
+		}
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusUnprocessableEntity,
+		Body:       string(jsonBody),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+}
+
+// Validate checks the whole request and returns every problem found. Lines
+// for the same product with the same options are merged into one line with
+// the summed quantity, which keeps the index of the first of them so that
+// later errors point at a line the client sent. Each line and the total of
+// each product must be at most MaxItemQuantity.
+func (r *CreateOrderRequest) Validate() []FieldError {
+	var fieldErrors []FieldError
+
+	if r.DeliveryAddressId == "" {
+		fieldErrors = append(fieldErrors, FieldError{Field: "deliveryAddressId", Message: "is required"})
+	}
+
+	if len(r.Items) == 0 {
+		return append(fieldErrors, FieldError{Field: "items", Message: "must contain at least one item"})
+	}
+
+	var merged []OrderItemRequest
+	firstIndex := map[string]int{}
+	quantities := map[string]int{}
+	var productIds []string
+	for i, item := range r.Items {
+		valid := true
+		if item.ProductId == "" {
+			fieldErrors = append(fieldErrors, FieldError{Field: fmt.Sprintf("items[%d].productId", i), Message: "is required"})
+			valid = false
+		}
+		if item.Quantity <= 0 {
+			fieldErrors = append(fieldErrors, FieldError{Field: fmt.Sprintf("items[%d].quantity", i), Message: "must be greater than 0"})
+			valid = false
+		} else if item.Quantity > MaxItemQuantity {
+			fieldErrors = append(fieldErrors, FieldError{Field: fmt.Sprintf("items[%d].quantity", i), Message: fmt.Sprintf("must be at most %d", MaxItemQuantity)})
+			valid = false
+		}
+		if !valid {
+			continue
+		}
+		item.index = i
+
+		if _, ok := quantities[item.ProductId]; !ok {
+			productIds = append(productIds, item.ProductId)
+		}
+		// Once a product is over the limit the order fails anyway. Adding
+		// no more keeps the sums far from overflowing.
+		if quantities[item.ProductId] > MaxItemQuantity {
+			continue
+		}
+		quantities[item.ProductId] += item.Quantity
+
+		key := item.lineKey()
+		if index, ok := firstIndex[key]; ok {
+			merged[index].Quantity += item.Quantity
+			continue
+		}
+		firstIndex[key] = len(merged)
+		merged = append(merged, item)
+	}
+
+	for _, productId := range productIds {
+		if quantities[productId] > MaxItemQuantity {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   fmt.Sprintf("items[%d].quantity", indexOfProduct(merged, productId)),
+				Message: fmt.Sprintf("total quantity of product %s must be at most %d", productId, MaxItemQuantity),
+			})
+		}
+	}
+
+	if len(merged) > models.MaxOrderItems {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   "items",
+			Message: fmt.Sprintf("must contain at most %d different lines", models.MaxOrderItems),
+		})
+	}
+
+	r.Items = merged
+	return fieldErrors
+}
+
+// lineKey identifies the lines that order the same product with the same
+// options.
+func (r *OrderItemRequest) lineKey() string {
+	variantIds := slices.Sorted(slices.Values(r.VariantIds))
+	modifierIds := slices.Sorted(slices.Values(r.ModifierIds))
+	return r.ProductId + "|" + strings.Join(variantIds, ",") + "|" + strings.Join(modifierIds, ",")
+}
+
+// indexOfProduct returns the request index of the first merged line for
+// productId.
+func indexOfProduct(items []OrderItemRequest, productId string) int {
+	for _, item := range items {
+		if item.ProductId == productId {
+			return item.index
+		}
+	}
+	return -1
+}
+
+func (r *CategoryRequest) Validate() []FieldError {
+	r.Name = strings.TrimSpace(r.Name)
+
+	var fieldErrors []FieldError
+	if r.Name == "" {
+		fieldErrors = append(fieldErrors, FieldError{Field: "name", Message: "is required"})
+	}
+	return fieldErrors
+}
+
+func (r *ProductRequest) Validate() []FieldError {
+	r.Name = strings.TrimSpace(r.Name)
+
+	var fieldErrors []FieldError
+	if r.Name == "" {
+		fieldErrors = append(fieldErrors, FieldError{Field: "name", Message: "is required"})
+	}
+	if r.CategoryId == "" {
+		fieldErrors = append(fieldErrors, FieldError{Field: "categoryId", Message: "is required"})
+	}
+
+	switch {
+	case r.Price == nil:
+		fieldErrors = append(fieldErrors, FieldError{Field: "price", Message: "is required"})
+	case r.Price.Amount <= 0:
+		fieldErrors = append(fieldErrors, FieldError{Field: "price.amount", Message: "must be greater than 0"})
+	case !models.IsCurrencyCode(r.Price.Currency):
+		fieldErrors = append(fieldErrors, FieldError{Field: "price.currency", Message: "must be an ISO 4217 currency code"})
+	}
+
+	return append(fieldErrors, r.validateOptions()...)
+}
+
+// validateOptions checks the variant and modifier groups of a product. Ids
+// must be unique across the product, as order lines pick options by id alone.
+// Variants may lower the price, as long as the cheapest combination still
+// costs something; modifiers may only add to it.
+func (r *ProductRequest) validateOptions() []FieldError {
+	var fieldErrors []FieldError
+	groupIds := map[string]bool{}
+	optionIds := map[string]bool{}
+
+	checkGroup := func(field string, id string, name *string) {
+		*name = strings.TrimSpace(*name)
+		switch {
+		case id == "":
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".id", Message: "is required"})
+		case groupIds[id]:
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".id", Message: "must be unique"})
+		}
+		groupIds[id] = true
+		if *name == "" {
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".name", Message: "is required"})
+		}
+	}
+	checkOption := func(field string, option *models.ProductOption) {
+		option.Name = strings.TrimSpace(option.Name)
+		switch {
+		case option.Id == "":
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".id", Message: "is required"})
+		case optionIds[option.Id]:
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".id", Message: "must be unique"})
+		}
+		optionIds[option.Id] = true
+		if option.Name == "" {
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".name", Message: "is required"})
+		}
+		if r.Price == nil {
+			return
+		}
+		if option.PriceDelta.Currency == "" {
+			option.PriceDelta.Currency = r.Price.Currency
+		}
+		if option.PriceDelta.Currency != r.Price.Currency {
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".priceDelta.currency", Message: "must match the currency of the price"})
+		}
+	}
+
+	var cheapest int64
+	for i := range r.VariantGroups {
+		group := &r.VariantGroups[i]
+		field := fmt.Sprintf("variantGroups[%d]", i)
+		checkGroup(field, group.Id, &group.Name)
+		if len(group.Variants) == 0 {
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".variants", Message: "must contain at least one variant"})
+			continue
+		}
+
+		lowest := group.Variants[0].PriceDelta.Amount
+		for j := range group.Variants {
+			checkOption(fmt.Sprintf("%s.variants[%d]", field, j), &group.Variants[j])
+			lowest = min(lowest, group.Variants[j].PriceDelta.Amount)
+		}
+		cheapest += lowest
+	}
+	if r.Price != nil && r.Price.Amount > 0 && r.Price.Amount+cheapest <= 0 {
+		fieldErrors = append(fieldErrors, FieldError{Field: "variantGroups", Message: "the cheapest combination of variants must cost more than 0"})
+	}
+
+	for i := range r.ModifierGroups {
+		group := &r.ModifierGroups[i]
+		field := fmt.Sprintf("modifierGroups[%d]", i)
+		checkGroup(field, group.Id, &group.Name)
+		if len(group.Modifiers) == 0 {
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".modifiers", Message: "must contain at least one modifier"})
+			continue
+		}
+		if group.MinSelect < 0 {
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".minSelect", Message: "must be at least 0"})
+		}
+		if group.MaxSelect < max(group.MinSelect, 1) || group.MaxSelect > len(group.Modifiers) {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   field + ".maxSelect",
+				Message: fmt.Sprintf("must be between %d and %d", max(group.MinSelect, 1), len(group.Modifiers)),
+			})
+		}
+
+		for j := range group.Modifiers {
+			modifier := &group.Modifiers[j]
+			modifierField := fmt.Sprintf("%s.modifiers[%d]", field, j)
+			checkOption(modifierField, modifier)
+			if modifier.PriceDelta.Amount < 0 {
+				fieldErrors = append(fieldErrors, FieldError{Field: modifierField + ".priceDelta.amount", Message: "must not be negative"})
+			}
+		}
+	}
+
+	return fieldErrors
+}
+
+func (r *AdRequest) Validate() []FieldError {
+	var fieldErrors []FieldError
+	if r.ImageUrl == "" {
+		fieldErrors = append(fieldErrors, FieldError{Field: "imageUrl", Message: "is required"})
+	}
+
+	if !models.IsValidAdActionType(r.ActionType) {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   "actionType",
+			Message: fmt.Sprintf("must be one of %s, %s or %s", models.AdActionProduct, models.AdActionCategory, models.AdActionURL),
+		})
+	}
+
+	if r.Action == "" {
+		fieldErrors = append(fieldErrors, FieldError{Field: "action", Message: "is required"})
+	} else if r.ActionType == models.AdActionURL && !isWebURL(r.Action) {
+		fieldErrors = append(fieldErrors, FieldError{Field: "action", Message: "must be an http or https URL"})
+	}
+
+	return fieldErrors
+}
+
+func isWebURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func (r *ProductAvailabilityRequest) Validate() []FieldError {
+	var fieldErrors []FieldError
+	if r.Available == nil {
+		fieldErrors = append(fieldErrors, FieldError{Field: "available", Message: "is required"})
+	}
+	if r.StockQuantity != nil && *r.StockQuantity < 0 {
+		fieldErrors = append(fieldErrors, FieldError{Field: "stockQuantity", Message: "must not be negative"})
+	}
+	return fieldErrors
+}
+
+func outOfStockFieldError(index int, product models.Product) FieldError {
+	field := fmt.Sprintf("items[%d].quantity", index)
+	switch {
+	case !product.Available:
+		return FieldError{Field: field, Message: fmt.Sprintf("product %s is unavailable", product.Id)}
+	case *product.StockQuantity == 0:
+		return FieldError{Field: field, Message: fmt.Sprintf("product %s is out of stock", product.Id)}
+	default:
+		return FieldError{Field: field, Message: fmt.Sprintf("only %d of product %s left in stock", *product.StockQuantity, product.Id)}
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCreateOrderRequestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		items       []OrderItemRequest
+		wantErrors  []FieldError
+		wantIndexes []int
+		wantTotals  []int
+	}{
+		{
+			name: "lines with the same options are merged",
+			items: []OrderItemRequest{
+				{ProductId: "pizza", Quantity: 1, ModifierIds: []string{"ham", "cheese"}},
+				{ProductId: "cola", Quantity: 2},
+				{ProductId: "pizza", Quantity: 2, ModifierIds: []string{"cheese", "ham"}},
+			},
+			wantIndexes: []int{0, 1},
+			wantTotals:  []int{3, 2},
+		},
+		{
+			name: "lines with different options stay apart",
+			items: []OrderItemRequest{
+				{ProductId: "pizza", Quantity: 1, VariantIds: []string{"small"}},
+				{ProductId: "pizza", Quantity: 1, VariantIds: []string{"large"}},
+			},
+			wantIndexes: []int{0, 1},
+			wantTotals:  []int{1, 1},
+		},
+		{
+			name: "merged lines keep the index of the line the client sent",
+			items: []OrderItemRequest{
+				{ProductId: "", Quantity: 1},
+				{ProductId: "cola", Quantity: 0},
+				{ProductId: "pizza", Quantity: 60},
+				{ProductId: "pizza", Quantity: 60, VariantIds: []string{"large"}},
+			},
+			wantErrors: []FieldError{
+				{Field: "items[0].productId", Message: "is required"},
+				{Field: "items[1].quantity", Message: "must be greater than 0"},
+				{Field: "items[2].quantity", Message: "total quantity of product pizza must be at most 100"},
+			},
+			wantIndexes: []int{2, 3},
+			wantTotals:  []int{60, 60},
+		},
+		{
+			name: "a line above the limit is rejected before merging",
+			items: []OrderItemRequest{
+				{ProductId: "pizza", Quantity: math.MaxInt},
+				{ProductId: "pizza", Quantity: 1},
+			},
+			wantErrors: []FieldError{
+				{Field: "items[0].quantity", Message: "must be at most 100"},
+			},
+			wantIndexes: []int{1},
+			wantTotals:  []int{1},
+		},
+		{
+			name: "lines that would overflow stop adding at the limit",
+			items: []OrderItemRequest{
+				{ProductId: "pizza", Quantity: 100},
+				{ProductId: "pizza", Quantity: 100},
+				{ProductId: "pizza", Quantity: 100},
+			},
+			wantErrors: []FieldError{
+				{Field: "items[0].quantity", Message: "total quantity of product pizza must be at most 100"},
+			},
+			wantIndexes: []int{0},
+			wantTotals:  []int{200},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := CreateOrderRequest{DeliveryAddressId: "address", Items: tt.items}
+			fieldErrors := request.Validate()
+			if !reflect.DeepEqual(fieldErrors, tt.wantErrors) {
+				t.Errorf("Validate() = %v, want %v", fieldErrors, tt.wantErrors)
+			}
+
+			var indexes, totals []int
+			for _, item := range request.Items {
+				indexes = append(indexes, item.index)
+				totals = append(totals, item.Quantity)
+			}
+			if !reflect.DeepEqual(indexes, tt.wantIndexes) {
+				t.Errorf("merged line indexes = %v, want %v", indexes, tt.wantIndexes)
+			}
+			if !reflect.DeepEqual(totals, tt.wantTotals) {
+				t.Errorf("merged line quantities = %v, want %v", totals, tt.wantTotals)
+			}
+		})
+	}
+}
@@ -1,38 +1,73 @@
 package main
 
 import (
+	"log"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/database"
 	"github.com/ZED-Magdy/delivery-cdk/lambda/handlers"
 	"github.com/ZED-Magdy/delivery-cdk/lambda/middlewares"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
 	"github.com/ZED-Magdy/delivery-cdk/lambda/router"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/services"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 )
 
-func setupRouter() *router.Router {
+func setupRouter(h *handlers.Handler) *router.Router {
 	r := router.NewRouter()
 	
 	authMiddleware := middlewares.AdaptAuthMiddleware()
+	idempotencyMiddleware := middlewares.IdempotencyMiddleware(h.Idempotency)
+	adminOnly := middlewares.RequireRole(models.RoleAdmin)
 
-	r.Add("/users/register", "POST", handlers.RegisterUser)
-	r.Add("/users/send-otp", "POST", handlers.SendOTP)
-	r.Add("/users/verify-otp", "POST", handlers.VerifyOTP)
-	r.Add("/ads", "GET", handlers.GetAds, authMiddleware)
-	r.Add("/categories", "GET", handlers.GetCategories, authMiddleware)
-	r.Add("/products/{categoryId}", "GET", handlers.GetProducts, authMiddleware)
-	r.Add("/orders", "POST", handlers.CreateOrder, authMiddleware)
-	r.Add("/orders", "GET", handlers.GetUserOrders, authMiddleware)
-	r.Add("/orders/{orderId}", "GET", handlers.GetOrderDetails, authMiddleware)
-	r.Add("/orders/{orderId}/cancel", "POST", handlers.CancelOrder, authMiddleware)
-	r.Add("/delivery-addresses", "POST", handlers.CreateDeliveryAddress, authMiddleware)
-	r.Add("/delivery-addresses", "GET", handlers.GetUserDeliveryAddresses, authMiddleware)
+	r.Add("/.well-known/jwks.json", "GET", h.GetJWKS)
+	r.Add("/users/register", "POST", h.RegisterUser)
+	r.Add("/users/send-otp", "POST", h.SendOTP)
+	r.Add("/users/verify-otp", "POST", h.VerifyOTP)
+	r.Add("/users/refresh", "POST", h.RefreshToken)
+	r.Add("/users/logout", "POST", h.Logout)
+	r.Add("/users/logout-all", "POST", h.LogoutAll, authMiddleware)
+	r.Add("/ads", "GET", h.GetAds, authMiddleware)
+	r.Add("/categories", "GET", h.GetCategories, authMiddleware)
+	r.Add("/products/{categoryId}", "GET", h.GetProducts, authMiddleware)
+	r.Add("/orders", "POST", h.CreateOrder, authMiddleware, idempotencyMiddleware)
+	r.Add("/orders", "GET", h.GetUserOrders, authMiddleware)
+	r.Add("/orders/{orderId}", "GET", h.GetOrderDetails, authMiddleware)
+	r.Add("/orders/{orderId}/cancel", "POST", h.CancelOrder, authMiddleware)
+	r.Add("/orders/{orderId}/history", "GET", h.GetOrderHistory, authMiddleware)
+	r.Add("/delivery-addresses", "POST", h.CreateDeliveryAddress, authMiddleware)
+	r.Add("/delivery-addresses", "GET", h.GetUserDeliveryAddresses, authMiddleware)
+	r.Add("/admin/users/{userId}/roles", "PUT", h.SetUserRoles, authMiddleware, adminOnly)
+	r.Add("/admin/categories", "POST", h.CreateCategory, authMiddleware, adminOnly)
+	r.Add("/admin/categories/{categoryId}", "PUT", h.UpdateCategory, authMiddleware, adminOnly)
+	r.Add("/admin/categories/{categoryId}", "DELETE", h.DeleteCategory, authMiddleware, adminOnly)
+	r.Add("/admin/products", "POST", h.CreateProduct, authMiddleware, adminOnly)
+	r.Add("/admin/products/{productId}", "PUT", h.UpdateProduct, authMiddleware, adminOnly)
+	r.Add("/admin/products/{productId}", "DELETE", h.DeleteProduct, authMiddleware, adminOnly)
+	r.Add("/admin/products/{productId}/availability", "PUT", h.SetProductAvailability, authMiddleware, middlewares.RequireRole(models.RoleAdmin, models.RoleStoreStaff))
+	r.Add("/admin/ads", "POST", h.CreateAd, authMiddleware, adminOnly)
+	r.Add("/admin/ads/{adId}", "PUT", h.UpdateAd, authMiddleware, adminOnly)
+	r.Add("/admin/ads/{adId}", "DELETE", h.DeleteAd, authMiddleware, adminOnly)
+	r.Add("/admin/catalog/import", "POST", h.ImportCatalog, authMiddleware, adminOnly)
+	r.Add("/admin/catalog/export", "GET", h.ExportCatalog, authMiddleware, adminOnly)
 	
 	return r
 }
 
 func main() {
+	repos, err := database.NewRepositories()
+	if err != nil {
+		log.Fatalf("failed to set up repositories: %v", err)
+	}
+
+	otpSender, err := services.NewOTPSender()
+	if err != nil {
+		log.Fatalf("failed to set up OTP sender: %v", err)
+	}
+
+	router := setupRouter(handlers.NewHandler(repos, services.SQSOrderQueue{}, otpSender))
+
 	lambda.Start(func (request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	router := setupRouter()
-	
 	handler, found := router.Match(request)
 	if !found {
 		return events.APIGatewayProxyResponse{
@@ -43,4 +78,4 @@ func main() {
 	
 	return handler(request)
 })
-}
\ No newline at end of file
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
 	"github.com/ZED-Magdy/delivery-cdk/lambda/utils"
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -50,10 +51,11 @@ func AuthMiddleware(handlerFunc HandlerFunc) HandlerFunc {
 			}, nil
 		}
 
-		request.Headers["X-User-ID"] = claims.UserID
-		request.Headers["X-User-Name"] = claims.Name
-		request.Headers["X-User-Phone"] = claims.Phone
-
-		return handlerFunc(request)
+		return handlerFunc(models.WithPrincipal(request, &models.Principal{
+			UserID: claims.UserID,
+			Name:   claims.Name,
+			Phone:  claims.Phone,
+			Roles:  models.RolesFromNames(claims.Roles),
+		}))
 	}
 }
@@ -0,0 +1,128 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/router"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyMiddleware makes a mutating route safe to retry. When a request
+// carries an Idempotency-Key header, the first response for that key is
+// stored and replayed for every retry with the same body, while reusing the
+// key with a different body is rejected with 422. Requests without the header
+// are passed through unchanged.
+//
+// Keys are scoped to the caller and the route, so it must run after the auth
+// middleware.
+func IdempotencyMiddleware(store models.IdempotencyRepository) router.MiddlewareFunc {
+	return func(next router.RouteHandler) router.RouteHandler {
+		return func(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			key := headerValue(request.Headers, IdempotencyKeyHeader)
+			if key == "" {
+				return next(request)
+			}
+
+			// Routes without authentication share one anonymous scope.
+			var userId string
+			if principal, err := models.RequestPrincipal(request); err == nil {
+				userId = principal.UserID
+			}
+
+			id := strings.Join([]string{userId, request.HTTPMethod, request.Resource, key}, "#")
+			hash := sha256.Sum256([]byte(request.Body))
+			requestHash := hex.EncodeToString(hash[:])
+
+			err := store.Claim(id, requestHash)
+			if errors.Is(err, models.ErrIdempotencyKeyExists) {
+				return replay(store, id, requestHash)
+			}
+			if err != nil {
+				return events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Body:       "Error checking idempotency key: " + err.Error(),
+				}, nil
+			}
+
+			response, err := next(request)
+			if err != nil || response.StatusCode >= http.StatusInternalServerError {
+				// Let the client retry requests that failed on our side
+				if releaseErr := store.Release(id); releaseErr != nil {
+					fmt.Printf("Error releasing idempotency key: %v\n", releaseErr)
+				}
+				return response, err
+			}
+
+			err = store.Complete(models.IdempotencyRecord{
+				Id:              id,
+				RequestHash:     requestHash,
+				ResponseStatus:  response.StatusCode,
+				ResponseBody:    response.Body,
+				ResponseHeaders: response.Headers,
+			})
+			if err != nil {
+				fmt.Printf("Error storing idempotent response: %v\n", err)
+			}
+
+			return response, nil
+		}
+	}
+}
+
+func replay(store models.IdempotencyRepository, id, requestHash string) (events.APIGatewayProxyResponse, error) {
+	record, err := store.Get(id)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error checking idempotency key: " + err.Error(),
+		}, nil
+	}
+
+	if record.RequestHash != requestHash {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       "Idempotency-Key was already used with a different request body",
+		}, nil
+	}
+
+	if record.Status != models.IdempotencyCompleted {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusConflict,
+			Body:       "A request with this Idempotency-Key is still being processed",
+		}, nil
+	}
+
+	headers := map[string]string{}
+	for name, value := range record.ResponseHeaders {
+		headers[name] = value
+	}
+	headers["Idempotent-Replayed"] = "true"
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: record.ResponseStatus,
+		Body:       record.ResponseBody,
+		Headers:    headers,
+	}, nil
+}
+
+// headerValue looks a header up case-insensitively, since API Gateway passes
+// header names through as the client sent them.
+func headerValue(headers map[string]string, name string) string {
+	if value, ok := headers[name]; ok {
+		return value
+	}
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
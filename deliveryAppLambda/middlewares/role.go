@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/router"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// RequireRole lets a request through only when the caller has at least one of
+// roles. It reads the principal set by the auth middleware, so it must be
+// listed after it:
+//
+//	r.Add("/admin/...", "POST", h.Handle, authMiddleware, middlewares.RequireRole(models.RoleAdmin))
+func RequireRole(roles ...models.Role) router.MiddlewareFunc {
+	return func(next router.RouteHandler) router.RouteHandler {
+		return func(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			principal, err := models.RequestPrincipal(request)
+			if err != nil {
+				return events.APIGatewayProxyResponse{
+					StatusCode: http.StatusUnauthorized,
+					Body:       "Unauthorized",
+				}, nil
+			}
+
+			if !principal.HasRole(roles...) {
+				return events.APIGatewayProxyResponse{
+					StatusCode: http.StatusForbidden,
+					Body:       "Forbidden",
+				}, nil
+			}
+
+			return next(request)
+		}
+	}
+}
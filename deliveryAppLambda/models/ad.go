@@ -1,11 +1,15 @@
 package models
 
-import (
-	"context"
+import "errors"
 
-	"github.com/ZED-Magdy/delivery-cdk/lambda/database"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+var ErrAdNotFound = errors.New("ad not found")
+
+// Ad action types. The action of an ad is the id of the product or category
+// it opens, or the URL it links to.
+const (
+	AdActionProduct  = "product"
+	AdActionCategory = "category"
+	AdActionURL      = "url"
 )
 
 type Ad struct {
@@ -15,25 +19,11 @@ type Ad struct {
 	ActionType string `json:"actionType" dynamodbav:"actionType"`
 }
 
-func ListAll() ([]Ad, error) {
-	adsTable := database.GetTables().AdsTable
-	ddbClient, err := database.NewDynamoDBClient(adsTable)
-	if err != nil {
-		return nil, err
-	}
-
-	data, err := ddbClient.Client.Scan(context.TODO(), &dynamodb.ScanInput{
-		TableName: &ddbClient.Table,
-	})
-	if err != nil {
-		return nil, err
+// IsValidAdActionType reports whether actionType is one of the Ad action types.
+func IsValidAdActionType(actionType string) bool {
+	switch actionType {
+	case AdActionProduct, AdActionCategory, AdActionURL:
+		return true
 	}
-
-	var ads []Ad
-	err = attributevalue.UnmarshalListOfMaps(data.Items, &ads)
-	if err != nil {
-		return nil, err
-	}
-
-	return ads, nil
+	return false
 }
@@ -0,0 +1,316 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// A Catalog is the set of categories and products exchanged with the
+// merchandising spreadsheets. Items are keyed by their SKU, or by their id
+// when they were created through the admin API without one, and products
+// name their category by its key.
+type Catalog struct {
+	Categories []CatalogCategory `json:"categories"`
+	Products   []CatalogProduct  `json:"products"`
+}
+
+type CatalogCategory struct {
+	Sku      string `json:"sku"`
+	Name     string `json:"name"`
+	ImageUrl string `json:"imageUrl"`
+
+	line int
+}
+
+type CatalogProduct struct {
+	Sku         string `json:"sku"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Price       Money  `json:"price"`
+	ImageUrl    string `json:"imageUrl"`
+	CategorySku string `json:"categorySku"`
+
+	line int
+}
+
+// CatalogValidationError lists every problem found in an imported catalog.
+type CatalogValidationError struct {
+	Problems []string
+}
+
+func (e *CatalogValidationError) Error() string {
+	return "invalid catalog: " + strings.Join(e.Problems, "; ")
+}
+
+type ChangeCounts struct {
+	Create    int `json:"create"`
+	Update    int `json:"update"`
+	Delete    int `json:"delete"`
+	Unchanged int `json:"unchanged"`
+}
+
+// CatalogDiff counts the changes an import makes to the stored catalog.
+type CatalogDiff struct {
+	Categories ChangeCounts `json:"categories"`
+	Products   ChangeCounts `json:"products"`
+}
+
+// CatalogImport holds the writes that make the stored catalog match an
+// imported one. The imported catalog is authoritative: stored items missing
+// from it are deleted.
+type CatalogImport struct {
+	Diff CatalogDiff
+
+	putCategories    []Category
+	deleteCategories []string
+	createProducts   []Product
+	updateProducts   []Product
+	deleteProducts   []string
+}
+
+func catalogKey(sku, id string) string {
+	if sku != "" {
+		return sku
+	}
+	return id
+}
+
+// ExportCatalog reads the stored catalog, ordered by key.
+func ExportCatalog(categories CategoryRepository, products ProductRepository) (*Catalog, error) {
+	storedCategories, err := ListAllPages(categories.ListAll)
+	if err != nil {
+		return nil, err
+	}
+	storedProducts, err := ListAllPages(products.ListAll)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{
+		Categories: []CatalogCategory{},
+		Products:   []CatalogProduct{},
+	}
+
+	categoryKeys := map[string]string{}
+	for _, category := range storedCategories {
+		key := catalogKey(category.Sku, category.Id)
+		categoryKeys[category.Id] = key
+		catalog.Categories = append(catalog.Categories, CatalogCategory{
+			Sku:      key,
+			Name:     category.Name,
+			ImageUrl: category.ImageUrl,
+		})
+	}
+
+	for _, product := range storedProducts {
+		categoryKey, ok := categoryKeys[product.CategoryId]
+		if !ok {
+			categoryKey = product.CategoryId
+		}
+		catalog.Products = append(catalog.Products, CatalogProduct{
+			Sku:         catalogKey(product.Sku, product.Id),
+			Name:        product.Name,
+			Description: product.Description,
+			Price:       product.Price,
+			ImageUrl:    product.ImageUrl,
+			CategorySku: categoryKey,
+		})
+	}
+
+	sort.Slice(catalog.Categories, func(i, j int) bool {
+		return catalog.Categories[i].Sku < catalog.Categories[j].Sku
+	})
+	sort.Slice(catalog.Products, func(i, j int) bool {
+		a, b := catalog.Products[i], catalog.Products[j]
+		if a.CategorySku != b.CategorySku {
+			return a.CategorySku < b.CategorySku
+		}
+		return a.Sku < b.Sku
+	})
+
+	return catalog, nil
+}
+
+// Validate returns every problem of the catalog in one
+// *CatalogValidationError.
+func (c *Catalog) Validate() error {
+	var problems []string
+
+	categorySkus := map[string]bool{}
+	for i, category := range c.Categories {
+		prefix := problemPrefix("categories", i, category.line)
+		switch {
+		case category.Sku == "":
+			problems = append(problems, prefix+"sku is required")
+		case categorySkus[category.Sku]:
+			problems = append(problems, prefix+"duplicate sku "+category.Sku)
+		default:
+			categorySkus[category.Sku] = true
+		}
+
+		if strings.TrimSpace(category.Name) == "" {
+			problems = append(problems, prefix+"name is required")
+		}
+	}
+
+	productSkus := map[string]bool{}
+	for i, product := range c.Products {
+		prefix := problemPrefix("products", i, product.line)
+		switch {
+		case product.Sku == "":
+			problems = append(problems, prefix+"sku is required")
+		case productSkus[product.Sku]:
+			problems = append(problems, prefix+"duplicate sku "+product.Sku)
+		default:
+			productSkus[product.Sku] = true
+		}
+
+		if strings.TrimSpace(product.Name) == "" {
+			problems = append(problems, prefix+"name is required")
+		}
+		if product.Price.Amount <= 0 {
+			problems = append(problems, prefix+"price must be greater than 0")
+		}
+		if !IsCurrencyCode(product.Price.Currency) {
+			problems = append(problems, prefix+"currency must be an ISO 4217 currency code")
+		}
+		if !categorySkus[product.CategorySku] {
+			problems = append(problems, prefix+"unknown category "+product.CategorySku)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &CatalogValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// problemPrefix locates a problem by CSV line when the catalog was read from
+// CSV, and by list index otherwise.
+func problemPrefix(list string, index, line int) string {
+	if line > 0 {
+		return fmt.Sprintf("line %d: ", line)
+	}
+	return fmt.Sprintf("%s[%d]: ", list, index)
+}
+
+// PlanCatalogImport compares an imported catalog with the stored one and
+// returns the writes to apply. It fails with *CatalogValidationError when the
+// catalog is invalid.
+func PlanCatalogImport(categories CategoryRepository, products ProductRepository, catalog *Catalog) (*CatalogImport, error) {
+	err := catalog.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	storedCategories, err := ListAllPages(categories.ListAll)
+	if err != nil {
+		return nil, err
+	}
+	storedProducts, err := ListAllPages(products.ListAll)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &CatalogImport{}
+
+	existingCategories := map[string]Category{}
+	for _, category := range storedCategories {
+		existingCategories[catalogKey(category.Sku, category.Id)] = category
+	}
+
+	categoryIds := map[string]string{}
+	for _, imported := range catalog.Categories {
+		category, ok := existingCategories[imported.Sku]
+		delete(existingCategories, imported.Sku)
+		if !ok {
+			category = Category{Id: uuid.New().String(), Sku: imported.Sku}
+		}
+		categoryIds[imported.Sku] = category.Id
+
+		updated := category
+		updated.Name = strings.TrimSpace(imported.Name)
+		updated.ImageUrl = imported.ImageUrl
+		switch {
+		case !ok:
+			plan.Diff.Categories.Create++
+		case updated != category:
+			plan.Diff.Categories.Update++
+		default:
+			plan.Diff.Categories.Unchanged++
+			continue
+		}
+		plan.putCategories = append(plan.putCategories, updated)
+	}
+	for _, category := range existingCategories {
+		plan.Diff.Categories.Delete++
+		plan.deleteCategories = append(plan.deleteCategories, category.Id)
+	}
+
+	existingProducts := map[string]Product{}
+	for _, product := range storedProducts {
+		existingProducts[catalogKey(product.Sku, product.Id)] = product
+	}
+
+	for _, imported := range catalog.Products {
+		product, ok := existingProducts[imported.Sku]
+		delete(existingProducts, imported.Sku)
+		if !ok {
+			product = Product{Id: uuid.New().String(), Sku: imported.Sku, Available: true}
+		}
+
+		updated := product
+		updated.Name = strings.TrimSpace(imported.Name)
+		updated.Description = imported.Description
+		updated.Price = imported.Price
+		updated.ImageUrl = imported.ImageUrl
+		updated.CategoryId = categoryIds[imported.CategorySku]
+		switch {
+		case !ok:
+			plan.Diff.Products.Create++
+			plan.createProducts = append(plan.createProducts, updated)
+		case !reflect.DeepEqual(updated, product):
+			plan.Diff.Products.Update++
+			plan.updateProducts = append(plan.updateProducts, updated)
+		default:
+			plan.Diff.Products.Unchanged++
+		}
+	}
+	for _, product := range existingProducts {
+		plan.Diff.Products.Delete++
+		plan.deleteProducts = append(plan.deleteProducts, product.Id)
+	}
+
+	return plan, nil
+}
+
+// Apply writes the planned changes. Categories are created before the
+// products that reference them and deleted after, so a failed import never
+// leaves products in a missing category. Existing products are updated one by
+// one rather than in batches, which would overwrite the stock that orders
+// reserve meanwhile. It is not atomic; running the same import again
+// completes a failed one.
+func (p *CatalogImport) Apply(categories CategoryRepository, products ProductRepository) error {
+	err := categories.BatchWrite(p.putCategories, nil)
+	if err != nil {
+		return err
+	}
+
+	err = products.BatchWrite(p.createProducts, p.deleteProducts)
+	if err != nil {
+		return err
+	}
+
+	for _, product := range p.updateProducts {
+		err = products.Update(product)
+		if err != nil {
+			return err
+		}
+	}
+
+	return categories.BatchWrite(nil, p.deleteCategories)
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	CatalogFormatJSON = "json"
+	CatalogFormatCSV  = "csv"
+)
+
+var ErrUnknownCatalogFormat = errors.New("unknown catalog format, expected json or csv")
+
+// catalogCSVHeader names the columns of a CSV catalog. Each row is a category
+// or a product, told apart by its type column; prices are decimal amounts in
+// major units, e.g. 12.50.
+var catalogCSVHeader = []string{"type", "sku", "name", "description", "price", "currency", "imageUrl", "categorySku"}
+
+// DecodeCatalog reads a catalog in the given format. CSV rows that cannot be
+// read are reported together in a *CatalogValidationError.
+func DecodeCatalog(r io.Reader, format string) (*Catalog, error) {
+	switch format {
+	case CatalogFormatJSON:
+		var catalog Catalog
+		err := json.NewDecoder(r).Decode(&catalog)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON catalog: %w", err)
+		}
+		return &catalog, nil
+	case CatalogFormatCSV:
+		return decodeCatalogCSV(r)
+	default:
+		return nil, ErrUnknownCatalogFormat
+	}
+}
+
+// EncodeCatalog writes a catalog in the given format.
+func EncodeCatalog(w io.Writer, format string, catalog *Catalog) error {
+	switch format {
+	case CatalogFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(catalog)
+	case CatalogFormatCSV:
+		return encodeCatalogCSV(w, catalog)
+	default:
+		return ErrUnknownCatalogFormat
+	}
+}
+
+func decodeCatalogCSV(r io.Reader) (*Catalog, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV catalog: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"type", "sku", "name"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("invalid CSV catalog: missing %s column", name)
+		}
+	}
+
+	catalog := &Catalog{}
+	var problems []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV catalog: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		switch rowType := strings.ToLower(field("type")); rowType {
+		case "category":
+			catalog.Categories = append(catalog.Categories, CatalogCategory{
+				Sku:      field("sku"),
+				Name:     field("name"),
+				ImageUrl: field("imageUrl"),
+				line:     line,
+			})
+		case "product":
+			currency := strings.ToUpper(field("currency"))
+			if currency == "" {
+				currency = DefaultCurrency()
+			}
+			price, err := strconv.ParseFloat(field("price"), 64)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("line %d: invalid price %q", line, field("price")))
+				continue
+			}
+
+			catalog.Products = append(catalog.Products, CatalogProduct{
+				Sku:         field("sku"),
+				Name:        field("name"),
+				Description: field("description"),
+				Price:       NewMoneyFromFloat(price, currency),
+				ImageUrl:    field("imageUrl"),
+				CategorySku: field("categorySku"),
+				line:        line,
+			})
+		default:
+			problems = append(problems, fmt.Sprintf("line %d: type must be category or product, got %q", line, rowType))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &CatalogValidationError{Problems: problems}
+	}
+	return catalog, nil
+}
+
+func encodeCatalogCSV(w io.Writer, catalog *Catalog) error {
+	writer := csv.NewWriter(w)
+	writer.Write(catalogCSVHeader)
+
+	for _, category := range catalog.Categories {
+		writer.Write([]string{"category", category.Sku, category.Name, "", "", "", category.ImageUrl, ""})
+	}
+	for _, product := range catalog.Products {
+		writer.Write([]string{
+			"product",
+			product.Sku,
+			product.Name,
+			product.Description,
+			product.Price.Decimal(),
+			product.Price.Currency,
+			product.ImageUrl,
+			product.CategorySku,
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -1,11 +1,10 @@
 package models
 
-import (
-	"context"
+import "errors"
 
-	"github.com/ZED-Magdy/delivery-cdk/lambda/database"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryNotEmpty = errors.New("category still has products")
 )
 
 // Category represents a product category in the system
@@ -13,28 +12,19 @@ type Category struct {
 	Id       string `json:"id" dynamodbav:"id"`
 	Name     string `json:"name" dynamodbav:"name"`
 	ImageUrl string `json:"imageUrl" dynamodbav:"imageUrl"`
+	Sku      string `json:"sku,omitempty" dynamodbav:"sku,omitempty"`
 }
 
-// ListAllCategories retrieves all categories from the database
-func ListAllCategories() ([]Category, error) {
-	categoriesTable := database.GetTables().CategoriesTable
-	ddbClient, err := database.NewDynamoDBClient(categoriesTable)
+// DeleteCategory deletes a category that has no products left. It fails with
+// ErrCategoryNotFound or ErrCategoryNotEmpty.
+func DeleteCategory(categories CategoryRepository, products ProductRepository, categoryId string) error {
+	page, err := products.ListByCategory(categoryId, PageRequest{Limit: 1})
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	data, err := ddbClient.Client.Scan(context.TODO(), &dynamodb.ScanInput{
-		TableName: &ddbClient.Table,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	var categories []Category
-	err = attributevalue.UnmarshalListOfMaps(data.Items, &categories)
-	if err != nil {
-		return nil, err
+	if len(page.Items) > 0 {
+		return ErrCategoryNotEmpty
 	}
 
-	return categories, nil
+	return categories.Delete(categoryId)
 }
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	IdempotencyInProgress = "in_progress"
+	IdempotencyCompleted  = "completed"
+
+	// IdempotencyKeyTTL is how long a stored response can be replayed.
+	IdempotencyKeyTTL = 24 * time.Hour
+)
+
+// IdempotencyRecord stores the outcome of a request made with an
+// Idempotency-Key so that retries of the same request get the same response.
+type IdempotencyRecord struct {
+	Id              string            `json:"id" dynamodbav:"id"`
+	RequestHash     string            `json:"requestHash" dynamodbav:"requestHash"`
+	Status          string            `json:"status" dynamodbav:"status"`
+	ResponseStatus  int               `json:"responseStatus,omitempty" dynamodbav:"responseStatus,omitempty"`
+	ResponseBody    string            `json:"responseBody,omitempty" dynamodbav:"responseBody,omitempty"`
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty" dynamodbav:"responseHeaders,omitempty"`
+	ExpiresAt       int64             `json:"expiresAt" dynamodbav:"expiresAt"`
+}
+
+var (
+	// ErrIdempotencyKeyExists is returned when claiming a key that is already
+	// claimed by an earlier request.
+	ErrIdempotencyKeyExists = errors.New("idempotency key already used")
+
+	ErrIdempotencyRecordNotFound = errors.New("idempotency record not found")
+)
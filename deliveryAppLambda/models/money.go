@@ -0,0 +1,190 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Money is an amount in the minor units of its ISO 4217 currency, e.g.
+// {Amount: 1250, Currency: "USD"} is $12.50. It is stored in DynamoDB as a
+// map of amount and currency.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// ErrMoneyOutOfRange is returned when an amount does not fit in an int64.
+var ErrMoneyOutOfRange = errors.New("amount out of range")
+
+// currencyExponents lists currencies whose minor unit is not 1/100.
+var currencyExponents = map[string]int{
+	"BHD": 3,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+}
+
+// DefaultCurrency is the currency of prices stored before Money existed. It
+// is read from the DEFAULT_CURRENCY environment variable and defaults to USD.
+func DefaultCurrency() string {
+	if currency := os.Getenv("DEFAULT_CURRENCY"); currency != "" {
+		return strings.ToUpper(currency)
+	}
+	return "USD"
+}
+
+// CurrencyExponent returns the number of decimal digits of a currency's minor
+// unit.
+func CurrencyExponent(currency string) int {
+	if exponent, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// NewMoneyFromFloat converts a decimal amount in major units, as stored by
+// older versions of the app, to Money.
+func NewMoneyFromFloat(value float64, currency string) Money {
+	scale := math.Pow10(CurrencyExponent(currency))
+	return Money{
+		Amount:   int64(math.Round(value * scale)),
+		Currency: currency,
+	}
+}
+
+// Multiply returns m times quantity. A product that overflows is an error.
+func (m Money) Multiply(quantity int) (Money, error) {
+	factor := int64(quantity)
+	amount := m.Amount * factor
+	if factor != 0 && (amount/factor != m.Amount || (factor == -1 && m.Amount == math.MinInt64)) {
+		return Money{}, fmt.Errorf("%s times %d: %w", m, quantity, ErrMoneyOutOfRange)
+	}
+	return Money{Amount: amount, Currency: m.Currency}, nil
+}
+
+// Add returns the sum of m and other. Adding amounts of different currencies
+// is an error, and so is a sum that overflows. A zero Money takes the
+// currency of the other operand.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency == "" {
+		m.Currency = other.Currency
+	}
+	if other.Currency != "" && other.Currency != m.Currency {
+		return Money{}, fmt.Errorf("cannot add %s to %s", other.Currency, m.Currency)
+	}
+	amount := m.Amount + other.Amount
+	if (other.Amount > 0 && amount < m.Amount) || (other.Amount < 0 && amount > m.Amount) {
+		return Money{}, fmt.Errorf("%s plus %s: %w", m, other, ErrMoneyOutOfRange)
+	}
+	return Money{Amount: amount, Currency: m.Currency}, nil
+}
+
+// String formats m in major units, e.g. "12.50 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// Decimal formats the amount of m in major units, e.g. "12.50".
+func (m Money) Decimal() string {
+	exponent := CurrencyExponent(m.Currency)
+	if exponent == 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	scale := int64(math.Pow10(exponent))
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/scale, exponent, amount%scale)
+}
+
+// IsCurrencyCode reports whether code looks like an ISO 4217 currency code.
+func IsCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// UnmarshalJSON accepts the {"amount", "currency"} object as well as a plain
+// decimal number in major units of the default currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var value float64
+	if err := json.Unmarshal(data, &value); err == nil {
+		*m = NewMoneyFromFloat(value, DefaultCurrency())
+		return nil
+	}
+
+	type money Money
+	var decoded money
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*m = Money(decoded)
+	if m.Currency == "" {
+		m.Currency = DefaultCurrency()
+	}
+	m.Currency = strings.ToUpper(m.Currency)
+	return nil
+}
+
+func (m Money) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	return &types.AttributeValueMemberM{
+		Value: map[string]types.AttributeValue{
+			"amount":   &types.AttributeValueMemberN{Value: strconv.FormatInt(m.Amount, 10)},
+			"currency": &types.AttributeValueMemberS{Value: m.Currency},
+		},
+	}, nil
+}
+
+// UnmarshalDynamoDBAttributeValue reads the map representation, and migrates
+// legacy float prices in major units of the default currency.
+func (m *Money) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberN:
+		value, err := strconv.ParseFloat(v.Value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid legacy money value %q: %w", v.Value, err)
+		}
+		*m = NewMoneyFromFloat(value, DefaultCurrency())
+		return nil
+	case *types.AttributeValueMemberM:
+		amount, ok := v.Value["amount"].(*types.AttributeValueMemberN)
+		if !ok {
+			return fmt.Errorf("money value is missing its amount")
+		}
+		parsed, err := strconv.ParseInt(amount.Value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid money amount %q: %w", amount.Value, err)
+		}
+		m.Amount = parsed
+		m.Currency = DefaultCurrency()
+		if currency, ok := v.Value["currency"].(*types.AttributeValueMemberS); ok && currency.Value != "" {
+			m.Currency = currency.Value
+		}
+		return nil
+	case *types.AttributeValueMemberNULL:
+		*m = Money{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported money attribute type %T", av)
+	}
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestNewMoneyFromFloat(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		currency string
+		want     Money
+	}{
+		{"cents", 12.5, "USD", Money{Amount: 1250, Currency: "USD"}},
+		{"rounds binary fractions", 0.29, "USD", Money{Amount: 29, Currency: "USD"}},
+		{"no minor unit", 1500, "JPY", Money{Amount: 1500, Currency: "JPY"}},
+		{"three decimals", 1.234, "KWD", Money{Amount: 1234, Currency: "KWD"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewMoneyFromFloat(tt.value, tt.currency); got != tt.want {
+				t.Errorf("NewMoneyFromFloat(%v, %q) = %+v, want %+v", tt.value, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyMultiply(t *testing.T) {
+	tests := []struct {
+		name     string
+		money    Money
+		quantity int
+		want     Money
+		wantErr  error
+	}{
+		{"quantity", Money{Amount: 1250, Currency: "USD"}, 3, Money{Amount: 3750, Currency: "USD"}, nil},
+		{"largest amount", Money{Amount: math.MaxInt64, Currency: "USD"}, 1, Money{Amount: math.MaxInt64, Currency: "USD"}, nil},
+		{"overflow", Money{Amount: 1250, Currency: "USD"}, math.MaxInt64, Money{}, ErrMoneyOutOfRange},
+		{"overflow to a positive amount", Money{Amount: math.MaxInt64, Currency: "USD"}, 3, Money{}, ErrMoneyOutOfRange},
+		{"negated smallest amount", Money{Amount: math.MinInt64, Currency: "USD"}, -1, Money{}, ErrMoneyOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.money.Multiply(tt.quantity)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Multiply(%d) error = %v, want %v", tt.quantity, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Multiply(%d) = %+v, want %+v", tt.quantity, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyAdd(t *testing.T) {
+	tests := []struct {
+		name         string
+		money, other Money
+		want         Money
+		wantErr      bool
+	}{
+		{"same currency", Money{Amount: 100, Currency: "USD"}, Money{Amount: 250, Currency: "USD"}, Money{Amount: 350, Currency: "USD"}, false},
+		{"zero takes the currency", Money{}, Money{Amount: 250, Currency: "EUR"}, Money{Amount: 250, Currency: "EUR"}, false},
+		{"other currency", Money{Amount: 100, Currency: "USD"}, Money{Amount: 250, Currency: "EUR"}, Money{}, true},
+		{"overflow", Money{Amount: math.MaxInt64, Currency: "USD"}, Money{Amount: 1, Currency: "USD"}, Money{}, true},
+		{"underflow", Money{Amount: math.MinInt64, Currency: "USD"}, Money{Amount: -1, Currency: "USD"}, Money{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.money.Add(tt.other)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Add() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Add() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	t.Setenv("DEFAULT_CURRENCY", "eur")
+
+	tests := []struct {
+		name    string
+		json    string
+		want    Money
+		wantErr bool
+	}{
+		{"legacy number", `9.99`, Money{Amount: 999, Currency: "EUR"}, false},
+		{"object", `{"amount": 1250, "currency": "usd"}`, Money{Amount: 1250, Currency: "USD"}, false},
+		{"object without currency", `{"amount": 300}`, Money{Amount: 300, Currency: "EUR"}, false},
+		{"string", `"9.99"`, Money{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, wantErr %v", tt.json, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Unmarshal(%s) = %+v, want %+v", tt.json, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyUnmarshalDynamoDBAttributeValue(t *testing.T) {
+	t.Setenv("DEFAULT_CURRENCY", "")
+
+	tests := []struct {
+		name    string
+		av      types.AttributeValue
+		want    Money
+		wantErr bool
+	}{
+		{"legacy float price", &types.AttributeValueMemberN{Value: "12.5"}, Money{Amount: 1250, Currency: "USD"}, false},
+		{"map", &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"amount":   &types.AttributeValueMemberN{Value: "1500"},
+			"currency": &types.AttributeValueMemberS{Value: "JPY"},
+		}}, Money{Amount: 1500, Currency: "JPY"}, false},
+		{"map without currency", &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"amount": &types.AttributeValueMemberN{Value: "99"},
+		}}, Money{Amount: 99, Currency: "USD"}, false},
+		{"null", &types.AttributeValueMemberNULL{Value: true}, Money{}, false},
+		{"map without amount", &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}}, Money{}, true},
+		{"malformed legacy price", &types.AttributeValueMemberN{Value: "abc"}, Money{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := got.UnmarshalDynamoDBAttributeValue(tt.av)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalDynamoDBAttributeValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("UnmarshalDynamoDBAttributeValue() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Migrated prices are written back in the map form.
+	av, err := Money{Amount: 1250, Currency: "USD"}.MarshalDynamoDBAttributeValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roundTrip Money
+	if err := roundTrip.UnmarshalDynamoDBAttributeValue(av); err != nil || roundTrip != (Money{Amount: 1250, Currency: "USD"}) {
+		t.Errorf("round trip = %+v, %v", roundTrip, err)
+	}
+}
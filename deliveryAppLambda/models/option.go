@@ -0,0 +1,135 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProductOption is a variant or modifier of a product. PriceDelta is added
+// to the product's price, and may be negative for variants such as a small
+// size.
+type ProductOption struct {
+	Id         string `json:"id" dynamodbav:"id"`
+	Name       string `json:"name" dynamodbav:"name"`
+	PriceDelta Money  `json:"priceDelta" dynamodbav:"priceDelta"`
+}
+
+// VariantGroup is a choice such as the size of a product. Every order line
+// picks exactly one of its variants.
+type VariantGroup struct {
+	Id       string          `json:"id" dynamodbav:"id"`
+	Name     string          `json:"name" dynamodbav:"name"`
+	Variants []ProductOption `json:"variants" dynamodbav:"variants"`
+}
+
+// ModifierGroup is a set of add-ons such as extra toppings, of which an order
+// line picks between MinSelect and MaxSelect.
+type ModifierGroup struct {
+	Id        string          `json:"id" dynamodbav:"id"`
+	Name      string          `json:"name" dynamodbav:"name"`
+	MinSelect int             `json:"minSelect" dynamodbav:"minSelect"`
+	MaxSelect int             `json:"maxSelect" dynamodbav:"maxSelect"`
+	Modifiers []ProductOption `json:"modifiers" dynamodbav:"modifiers"`
+}
+
+// OrderItemOption is a variant or modifier picked for an order line, copied
+// from the product when the order was placed.
+type OrderItemOption struct {
+	GroupId    string `json:"groupId" dynamodbav:"groupId"`
+	GroupName  string `json:"groupName" dynamodbav:"groupName"`
+	Id         string `json:"id" dynamodbav:"id"`
+	Name       string `json:"name" dynamodbav:"name"`
+	PriceDelta Money  `json:"priceDelta" dynamodbav:"priceDelta"`
+}
+
+// OptionSelectionError describes why the options picked for a product were
+// rejected.
+type OptionSelectionError struct {
+	Problems []string
+}
+
+func (e *OptionSelectionError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// SelectOptions checks the variants and modifiers picked for one unit of the
+// product against its groups, and returns them in the order of the product's
+// groups together with the resulting unit price. It fails with
+// *OptionSelectionError.
+func (p *Product) SelectOptions(variantIds, modifierIds []string) ([]OrderItemOption, Money, error) {
+	var problems []string
+	variants := pickOptions(variantIds, "variant", &problems)
+	modifiers := pickOptions(modifierIds, "modifier", &problems)
+
+	var options []OrderItemOption
+	for _, group := range p.VariantGroups {
+		chosen := chooseOptions(group.Id, group.Name, group.Variants, variants)
+		if len(chosen) != 1 {
+			problems = append(problems, fmt.Sprintf("pick exactly one %s", group.Name))
+		}
+		options = append(options, chosen...)
+	}
+	for _, group := range p.ModifierGroups {
+		chosen := chooseOptions(group.Id, group.Name, group.Modifiers, modifiers)
+		if len(chosen) < group.MinSelect || len(chosen) > group.MaxSelect {
+			problems = append(problems, fmt.Sprintf("pick between %d and %d of %s", group.MinSelect, group.MaxSelect, group.Name))
+		}
+		options = append(options, chosen...)
+	}
+
+	for _, id := range variantIds {
+		if !variants[id] {
+			problems = append(problems, "unknown variant "+id)
+		}
+	}
+	for _, id := range modifierIds {
+		if !modifiers[id] {
+			problems = append(problems, "unknown modifier "+id)
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, Money{}, &OptionSelectionError{Problems: problems}
+	}
+
+	price := p.Price
+	for _, option := range options {
+		var err error
+		price, err = price.Add(option.PriceDelta)
+		if err != nil {
+			return nil, Money{}, err
+		}
+	}
+	return options, price, nil
+}
+
+// pickOptions returns the picked ids, mapped to false until a group of the
+// product claims them.
+func pickOptions(ids []string, kind string, problems *[]string) map[string]bool {
+	picked := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if _, ok := picked[id]; ok {
+			*problems = append(*problems, fmt.Sprintf("%s %s is picked more than once", kind, id))
+		}
+		picked[id] = false
+	}
+	return picked
+}
+
+func chooseOptions(groupId, groupName string, options []ProductOption, picked map[string]bool) []OrderItemOption {
+	var chosen []OrderItemOption
+	for _, option := range options {
+		if _, ok := picked[option.Id]; !ok {
+			continue
+		}
+		picked[option.Id] = true
+		chosen = append(chosen, OrderItemOption{
+			GroupId:    groupId,
+			GroupName:  groupName,
+			Id:         option.Id,
+			Name:       option.Name,
+			PriceDelta: option.PriceDelta,
+		})
+	}
+	return chosen
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ActorRoleCustomer = "customer"
+	ActorRoleSystem   = "system"
+)
+
+// Actor identifies who changed an order status.
+type Actor struct {
+	ID   string
+	Role string
+}
+
+// sortKeyLayout formats the time of a status change with a fixed number of
+// fractional digits, so that the strings sort in time order.
+const sortKeyLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// OrderStatusChange is an append-only entry of an order's status history.
+// Entries are keyed by orderId and sorted by sortKey, which is the UTC time
+// of the change followed by a random suffix that keeps changes made in the
+// same instant apart. ChangedAt is the plain time of the change.
+type OrderStatusChange struct {
+	OrderId   string      `json:"orderId" dynamodbav:"orderId"`
+	SortKey   string      `json:"-" dynamodbav:"sortKey"`
+	ChangedAt time.Time   `json:"changedAt" dynamodbav:"changedAt"`
+	From      OrderStatus `json:"from,omitempty" dynamodbav:"from,omitempty"`
+	To        OrderStatus `json:"to" dynamodbav:"to"`
+	ActorId   string      `json:"actorId" dynamodbav:"actorId"`
+	ActorRole string      `json:"actorRole" dynamodbav:"actorRole"`
+	Reason    string      `json:"reason,omitempty" dynamodbav:"reason,omitempty"`
+}
+
+func NewOrderStatusChange(orderId string, from, to OrderStatus, actor Actor, reason string) OrderStatusChange {
+	now := time.Now().UTC()
+	return OrderStatusChange{
+		OrderId:   orderId,
+		SortKey:   statusChangeSortKey(now),
+		ChangedAt: now,
+		From:      from,
+		To:        to,
+		ActorId:   actor.ID,
+		ActorRole: actor.Role,
+		Reason:    reason,
+	}
+}
+
+func statusChangeSortKey(t time.Time) string {
+	return t.UTC().Format(sortKeyLayout) + "#" + uuid.New().String()[:8]
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStatusChangeSortKeySortsByTime(t *testing.T) {
+	base := time.Date(2026, 3, 1, 10, 0, 5, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		earlier, later time.Time
+	}{
+		{"whole second before fraction", base, base.Add(100 * time.Millisecond)},
+		{"shorter fraction before longer", base.Add(250 * time.Millisecond), base.Add(500 * time.Millisecond)},
+		{"nanoseconds", base.Add(time.Nanosecond), base.Add(2 * time.Nanosecond)},
+		{"next second", base.Add(999 * time.Millisecond), base.Add(time.Second)},
+		{"local time zone", base.In(time.FixedZone("UTC+3", 3*3600)), base.Add(time.Millisecond)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			earlier, later := statusChangeSortKey(tt.earlier), statusChangeSortKey(tt.later)
+			if earlier >= later {
+				t.Errorf("statusChangeSortKey(%v) = %q, want it before statusChangeSortKey(%v) = %q", tt.earlier, earlier, tt.later, later)
+			}
+		})
+	}
+}
+
+func TestStatusChangeSortKeyIsUniqueWithinAnInstant(t *testing.T) {
+	now := time.Now()
+	if first, second := statusChangeSortKey(now), statusChangeSortKey(now); first == second {
+		t.Errorf("statusChangeSortKey returned %q twice for the same instant", first)
+	}
+}
+
+func TestOrderStatusChangeJSON(t *testing.T) {
+	change := NewOrderStatusChange("order-1", StatusPending, StatusConfirmed, Actor{ID: "staff-1", Role: "staff"}, "")
+
+	body, err := json.Marshal(change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	changedAt, _ := decoded["changedAt"].(string)
+	if _, err := time.Parse(time.RFC3339, changedAt); err != nil {
+		t.Errorf("changedAt = %q is not an RFC 3339 time: %v", changedAt, err)
+	}
+	if _, ok := decoded["sortKey"]; ok {
+		t.Errorf("sortKey is part of the response: %s", body)
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+import "testing"
+
+func TestCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to OrderStatus
+		want     bool
+	}{
+		{StatusPending, StatusConfirmed, true},
+		{StatusPending, StatusCanceled, true},
+		{StatusPending, StatusDelivering, false},
+		{StatusConfirmed, StatusDelivering, true},
+		{StatusConfirmed, StatusCanceled, true},
+		{StatusConfirmed, StatusPending, false},
+		{StatusDelivering, StatusDelivered, true},
+		{StatusDelivering, StatusCanceled, false},
+		{StatusDelivered, StatusCanceled, false},
+		{StatusCanceled, StatusPending, false},
+		{StatusPending, StatusPending, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+				t.Errorf("%s.CanTransitionTo(%s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
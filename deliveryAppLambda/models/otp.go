@@ -0,0 +1,206 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/utils"
+)
+
+const (
+	DefaultOTPLength = 6
+	MinOTPLength     = 4
+	MaxOTPLength     = 10
+
+	// OTPTTL is how long a sent OTP can be used.
+	OTPTTL = 2 * time.Minute
+
+	// MaxOTPAttempts failed verifications lock verification for
+	// OTPLockoutDuration.
+	MaxOTPAttempts     = 5
+	OTPLockoutDuration = 15 * time.Minute
+
+	// OTPResendCooldown is the minimum time between two OTPs sent to the same
+	// phone, and MaxOTPSendsPerDay caps them per UTC day.
+	OTPResendCooldown = time.Minute
+	MaxOTPSendsPerDay = 5
+
+	// otpUpdateAttempts bounds the retries of an OTP state update that lost a
+	// race with a concurrent request for the same user.
+	otpUpdateAttempts = 3
+)
+
+// ErrOTPStateChanged is returned by UserRepository.UpdateOTP when the OTP
+// state was changed by another request since it was read.
+var ErrOTPStateChanged = errors.New("OTP state changed concurrently")
+
+// OTPRateLimitError is returned when an OTP limit is hit. RetryAfter is how
+// long until the request can succeed.
+type OTPRateLimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *OTPRateLimitError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+var errOTPContention = &OTPRateLimitError{
+	Reason:     "too many concurrent OTP requests",
+	RetryAfter: time.Second,
+}
+
+// OTPState is the OTP bookkeeping stored on a user. Users are unique per
+// phone, so its send limits apply per phone number. OTPVersion is bumped by
+// every change and guards updates against concurrent requests.
+type OTPState struct {
+	OTPHash           string    `json:"-" dynamodbav:"otp_hash"`
+	OTPExpiresAt      time.Time `json:"-" dynamodbav:"otp_expires_at"`
+	OTPFailedAttempts int       `json:"-" dynamodbav:"otp_failed_attempts"`
+	OTPLockedUntil    time.Time `json:"-" dynamodbav:"otp_locked_until"`
+	OTPLastSentAt     time.Time `json:"-" dynamodbav:"otp_last_sent_at"`
+	OTPSendDay        string    `json:"-" dynamodbav:"otp_send_day"`
+	OTPSendCount      int       `json:"-" dynamodbav:"otp_send_count"`
+	OTPVersion        int       `json:"-" dynamodbav:"otp_version"`
+}
+
+func otpSendDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// sendLimit returns an *OTPRateLimitError when no OTP may be sent at now.
+func (s OTPState) sendLimit(now time.Time) error {
+	if now.Before(s.OTPLockedUntil) {
+		return &OTPRateLimitError{
+			Reason:     "too many failed OTP attempts",
+			RetryAfter: s.OTPLockedUntil.Sub(now),
+		}
+	}
+
+	if next := s.OTPLastSentAt.Add(OTPResendCooldown); now.Before(next) {
+		return &OTPRateLimitError{
+			Reason:     "an OTP was sent recently",
+			RetryAfter: next.Sub(now),
+		}
+	}
+
+	if s.OTPSendDay == otpSendDay(now) && s.OTPSendCount >= MaxOTPSendsPerDay {
+		tomorrow := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		return &OTPRateLimitError{
+			Reason:     "daily OTP limit reached",
+			RetryAfter: tomorrow.Sub(now),
+		}
+	}
+
+	return nil
+}
+
+// OTPSender delivers a one-time password to a phone number. Implementations
+// live in the services package.
+type OTPSender interface {
+	SendOTP(phone, otp string) error
+}
+
+// OTPLength returns the OTP length configured with OTP_LENGTH, or
+// DefaultOTPLength when it is unset or out of range.
+func OTPLength() int {
+	length, err := strconv.Atoi(os.Getenv("OTP_LENGTH"))
+	if err != nil || length < MinOTPLength || length > MaxOTPLength {
+		return DefaultOTPLength
+	}
+	return length
+}
+
+// GenerateOTP returns a string of length random decimal digits drawn from
+// crypto/rand.
+func GenerateOTP(length int) (string, error) {
+	digits := make([]byte, length)
+	for i := range digits {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		digits[i] = byte('0' + n.Int64())
+	}
+	return string(digits), nil
+}
+
+// HashOTP computes the HMAC-SHA256 of an OTP and the user id under the
+// server-side utils.OTPHashSecret, which is what gets stored instead of the
+// OTP itself. Without the key, a leaked hash cannot be checked against the
+// few possible codes.
+func HashOTP(userId, otp string) (string, error) {
+	mac, err := otpMAC(userId, otp)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(mac), nil
+}
+
+// CheckOTP reports whether otp matches the stored hash for the user.
+func CheckOTP(userId, otp, otpHash string) (bool, error) {
+	stored, err := hex.DecodeString(otpHash)
+	if err != nil || len(stored) == 0 {
+		return false, nil
+	}
+
+	mac, err := otpMAC(userId, otp)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal(mac, stored), nil
+}
+
+func otpMAC(userId, otp string) ([]byte, error) {
+	secret, err := utils.OTPHashSecret.Value()
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(userId))
+	mac.Write([]byte{0})
+	mac.Write([]byte(otp))
+	return mac.Sum(nil), nil
+}
+
+// issueOTP generates an OTP for user, records its hash and the send on the
+// user's OTP state and returns the plaintext for delivery. It fails with
+// *OTPRateLimitError when a send limit is hit.
+func issueOTP(user *User, now time.Time) (string, error) {
+	err := user.OTPState.sendLimit(now)
+	if err != nil {
+		return "", err
+	}
+
+	otp, err := GenerateOTP(OTPLength())
+	if err != nil {
+		return "", err
+	}
+
+	otpHash, err := HashOTP(user.ID, otp)
+	if err != nil {
+		return "", err
+	}
+
+	state := &user.OTPState
+	state.OTPHash = otpHash
+	state.OTPExpiresAt = now.Add(OTPTTL)
+	state.OTPFailedAttempts = 0
+	state.OTPLastSentAt = now
+	if day := otpSendDay(now); state.OTPSendDay != day {
+		state.OTPSendDay = day
+		state.OTPSendCount = 0
+	}
+	state.OTPSendCount++
+	state.OTPVersion++
+	return otp, nil
+}
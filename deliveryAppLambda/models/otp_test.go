@@ -0,0 +1,156 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/database/memory"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/services"
+)
+
+const phone = "+15550100"
+
+func setOTPHashSecret(t *testing.T) {
+	t.Helper()
+	t.Setenv("OTP_HASH_SECRET_ARN", "")
+	t.Setenv("OTP_HASH_SECRET", "test-otp-hash-secret")
+}
+
+// register signs up a user and returns the OTP they were sent.
+func register(t *testing.T, users *memory.UserRepository, sender *services.RecordingOTPSender) string {
+	t.Helper()
+	_, err := models.RegisterUser(users, sender, models.UserRegistrationInput{Name: "Sam", Phone: phone})
+	if err != nil {
+		t.Fatalf("RegisterUser() error = %v", err)
+	}
+	otp, ok := sender.Last(phone)
+	if !ok {
+		t.Fatal("no OTP sent on registration")
+	}
+	return otp
+}
+
+func wrongOTP(otp string) string {
+	wrong := []byte(otp)
+	wrong[0] = '0' + (wrong[0]-'0'+1)%10
+	return string(wrong)
+}
+
+func TestVerifyOTP(t *testing.T) {
+	tests := []struct {
+		name         string
+		wrongGuesses int
+		wantLocked   bool
+	}{
+		{"correct code", 0, false},
+		{"correct code after failed guesses", models.MaxOTPAttempts - 1, false},
+		{"locked after too many failed guesses", models.MaxOTPAttempts, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setOTPHashSecret(t)
+			users := memory.NewUserRepository()
+			sender := &services.RecordingOTPSender{}
+			otp := register(t, users, sender)
+
+			for i := 0; i < tt.wrongGuesses; i++ {
+				_, err := models.VerifyOTP(users, models.OTPVerificationInput{Phone: phone, OTP: wrongOTP(otp)})
+				if !errors.Is(err, models.ErrInvalidOTP) {
+					t.Fatalf("guess %d: VerifyOTP() error = %v, want %v", i+1, err, models.ErrInvalidOTP)
+				}
+			}
+
+			user, err := models.VerifyOTP(users, models.OTPVerificationInput{Phone: phone, OTP: otp})
+			var rateLimitErr *models.OTPRateLimitError
+			if locked := errors.As(err, &rateLimitErr); locked != tt.wantLocked {
+				t.Fatalf("VerifyOTP() error = %v, want locked %v", err, tt.wantLocked)
+			}
+			if tt.wantLocked {
+				if rateLimitErr.RetryAfter <= 0 || rateLimitErr.RetryAfter > models.OTPLockoutDuration {
+					t.Errorf("RetryAfter = %s, want within %s", rateLimitErr.RetryAfter, models.OTPLockoutDuration)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyOTP() error = %v", err)
+			}
+			if user.Phone != phone {
+				t.Errorf("VerifyOTP() user phone = %q, want %q", user.Phone, phone)
+			}
+
+			// A code can be used once.
+			_, err = models.VerifyOTP(users, models.OTPVerificationInput{Phone: phone, OTP: otp})
+			if !errors.Is(err, models.ErrInvalidOTP) {
+				t.Errorf("second VerifyOTP() error = %v, want %v", err, models.ErrInvalidOTP)
+			}
+		})
+	}
+}
+
+func TestSendOTPLimits(t *testing.T) {
+	now := time.Now()
+	today := now.UTC().Format(time.DateOnly)
+
+	tests := []struct {
+		name       string
+		state      models.OTPState
+		wantReason string
+	}{
+		{"first code", models.OTPState{}, ""},
+		{"after the cooldown", models.OTPState{OTPLastSentAt: now.Add(-models.OTPResendCooldown), OTPSendDay: today, OTPSendCount: 1}, ""},
+		{"within the cooldown", models.OTPState{OTPLastSentAt: now, OTPSendDay: today, OTPSendCount: 1}, "an OTP was sent recently"},
+		{"daily limit reached", models.OTPState{OTPSendDay: today, OTPSendCount: models.MaxOTPSendsPerDay}, "daily OTP limit reached"},
+		{"daily limit of another day", models.OTPState{OTPSendDay: "2020-01-01", OTPSendCount: models.MaxOTPSendsPerDay}, ""},
+		{"verification locked", models.OTPState{OTPLockedUntil: now.Add(time.Minute)}, "too many failed OTP attempts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setOTPHashSecret(t)
+			users := memory.NewUserRepository()
+			sender := &services.RecordingOTPSender{}
+			err := users.Create(models.User{ID: "user-1", Name: "Sam", Phone: phone, OTPState: tt.state})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = models.SendOTP(users, sender, models.SendOTPInput{Phone: phone})
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatalf("SendOTP() error = %v", err)
+				}
+				if _, ok := sender.Last(phone); !ok {
+					t.Error("SendOTP() did not send an OTP")
+				}
+				return
+			}
+
+			var rateLimitErr *models.OTPRateLimitError
+			if !errors.As(err, &rateLimitErr) || rateLimitErr.Reason != tt.wantReason {
+				t.Fatalf("SendOTP() error = %v, want rate limit %q", err, tt.wantReason)
+			}
+			if rateLimitErr.RetryAfter <= 0 {
+				t.Errorf("RetryAfter = %s, want positive", rateLimitErr.RetryAfter)
+			}
+			if sent := sender.Sent(); len(sent) != 0 {
+				t.Errorf("SendOTP() sent %d OTPs while limited", len(sent))
+			}
+		})
+	}
+}
+
+func TestRegisterUserDuplicatePhone(t *testing.T) {
+	setOTPHashSecret(t)
+	users := memory.NewUserRepository()
+	sender := &services.RecordingOTPSender{}
+	register(t, users, sender)
+
+	_, err := models.RegisterUser(users, sender, models.UserRegistrationInput{Name: "Alex", Phone: phone})
+	if !errors.Is(err, models.ErrPhoneAlreadyRegistered) {
+		t.Fatalf("RegisterUser() error = %v, want %v", err, models.ErrPhoneAlreadyRegistered)
+	}
+	if sent := sender.Sent(); len(sent) != 1 {
+		t.Errorf("sent %d OTPs, want 1", len(sent))
+	}
+}
@@ -0,0 +1,57 @@
+package models
+
+import "errors"
+
+// ErrInvalidCursor is returned when a page cursor was not issued for the
+// listing it is used with.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageRequest asks for one page of a listing. Cursor is the opaque
+// NextCursor of the previous page, or empty for the first page.
+type PageRequest struct {
+	Limit  int
+	Cursor string
+}
+
+// Page is one page of a listing. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// PageLimit returns the requested page size clamped to MaxPageLimit, or
+// DefaultPageLimit when none was requested.
+func (p PageRequest) PageLimit() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultPageLimit
+	case p.Limit > MaxPageLimit:
+		return MaxPageLimit
+	default:
+		return p.Limit
+	}
+}
+
+// ListAllPages drains a paginated listing, fetching MaxPageLimit items at a
+// time.
+func ListAllPages[T any](list func(PageRequest) (*Page[T], error)) ([]T, error) {
+	var items []T
+	request := PageRequest{Limit: MaxPageLimit}
+	for {
+		page, err := list(request)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+
+		if page.NextCursor == "" {
+			return items, nil
+		}
+		request.Cursor = page.NextCursor
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ErrUnauthenticated is returned when a request carries no principal, i.e.
+// it did not pass through the auth middleware.
+var ErrUnauthenticated = errors.New("request is not authenticated")
+
+// Principal is the authenticated caller of a request, taken from its verified
+// access token by the auth middleware.
+type Principal struct {
+	UserID string
+	Name   string
+	Phone  string
+	Roles  []Role
+}
+
+// HasRole reports whether the principal has any of roles.
+func (p *Principal) HasRole(roles ...Role) bool {
+	return hasAnyRole(p.Roles, roles)
+}
+
+// principalKey is where the principal is kept in the request's authorizer
+// context. API Gateway only fills that context from authorizers and never
+// from client input, and a *Principal cannot be decoded from an event.
+const principalKey = "principal"
+
+// WithPrincipal returns a copy of request carrying principal.
+func WithPrincipal(request events.APIGatewayProxyRequest, principal *Principal) events.APIGatewayProxyRequest {
+	authorizer := make(map[string]interface{}, len(request.RequestContext.Authorizer)+1)
+	for key, value := range request.RequestContext.Authorizer {
+		authorizer[key] = value
+	}
+	authorizer[principalKey] = principal
+	request.RequestContext.Authorizer = authorizer
+	return request
+}
+
+// RequestPrincipal returns the authenticated caller of a request, or
+// ErrUnauthenticated. Handlers read the caller only through it.
+func RequestPrincipal(request events.APIGatewayProxyRequest) (*Principal, error) {
+	principal, ok := request.RequestContext.Authorizer[principalKey].(*Principal)
+	if !ok || principal == nil {
+		return nil, ErrUnauthenticated
+	}
+	return principal, nil
+}
@@ -1,78 +1,91 @@
 package models
 
 import (
-	"context"
-	"fmt"
+	"errors"
+	"strings"
 
-	"github.com/ZED-Magdy/delivery-cdk/lambda/database"
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+var ErrProductNotFound = errors.New("product not found")
+
+// Product is an item of the catalog. Products whose StockQuantity is nil are
+// not stock-tracked and can be ordered in any quantity while available.
+// Price is the price of the product before its variants and modifiers.
 type Product struct {
-	Id          string  `json:"id" dynamodbav:"id"`
-	Name        string  `json:"name" dynamodbav:"name"`
-	Description string  `json:"description" dynamodbav:"description"`
-	Price       float64 `json:"price" dynamodbav:"price"`
-	ImageUrl    string  `json:"imageUrl" dynamodbav:"imageUrl"`
-	CategoryId  string  `json:"categoryId" dynamodbav:"categoryId"`
+	Id             string          `json:"id" dynamodbav:"id"`
+	Name           string          `json:"name" dynamodbav:"name"`
+	Description    string          `json:"description" dynamodbav:"description"`
+	Price          Money           `json:"price" dynamodbav:"price"`
+	ImageUrl       string          `json:"imageUrl" dynamodbav:"imageUrl"`
+	CategoryId     string          `json:"categoryId" dynamodbav:"categoryId"`
+	Sku            string          `json:"sku,omitempty" dynamodbav:"sku,omitempty"`
+	Available      bool            `json:"available" dynamodbav:"available"`
+	StockQuantity  *int            `json:"stockQuantity,omitempty" dynamodbav:"stockQuantity,omitempty"`
+	VariantGroups  []VariantGroup  `json:"variantGroups,omitempty" dynamodbav:"variantGroups,omitempty"`
+	ModifierGroups []ModifierGroup `json:"modifierGroups,omitempty" dynamodbav:"modifierGroups,omitempty"`
 }
 
-func ListAllProducts(categoryId string) ([]Product, error) {
-	productsTable := database.GetTables().ProductsTable
-	ddbClient, err := database.NewDynamoDBClient(productsTable)
+// UnmarshalDynamoDBAttributeValue treats products stored before availability
+// existed as available.
+func (p *Product) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
+	type product Product
+	decoded := product{Available: true}
+	err := attributevalue.Unmarshal(av, &decoded)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	data, err := ddbClient.Client.Scan(context.TODO(), &dynamodb.ScanInput{
-		TableName: &ddbClient.Table,
-		FilterExpression: aws.String("categoryId = :categoryId"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":categoryId": &types.AttributeValueMemberS{Value: categoryId},
-		},
-	})
+	*p = Product(decoded)
+	return nil
+}
 
-	if err != nil {
-		return nil, err
-	}
+// CanOrder reports whether quantity units of the product can be ordered.
+func (p *Product) CanOrder(quantity int) bool {
+	return p.Available && (p.StockQuantity == nil || *p.StockQuantity >= quantity)
+}
 
-	var products []Product
-	err = attributevalue.UnmarshalListOfMaps(data.Items, &products)
-	if err != nil {
-		return nil, err
-	}
+// ProductsNotFoundError lists every requested product that does not exist.
+type ProductsNotFoundError struct {
+	Ids []string
+}
 
-	return products, nil
+func (e *ProductsNotFoundError) Error() string {
+	return "products not found: " + strings.Join(e.Ids, ", ")
 }
 
-func GetProductById(productId string) (*Product, error) {
-	productsTable := database.GetTables().ProductsTable
-	ddbClient, err := database.NewDynamoDBClient(productsTable)
-	if err != nil {
-		return nil, err
-	}
+// OutOfStockError lists the products of an order that became unavailable or
+// ran out of stock before the order was stored.
+type OutOfStockError struct {
+	Ids []string
+}
 
-	result, err := ddbClient.Client.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: &ddbClient.Table,
-		Key: map[string]types.AttributeValue{
-			"id": &types.AttributeValueMemberS{Value: productId},
-		},
-	})
-	if err != nil {
-		return nil, err
-	}
+func (e *OutOfStockError) Error() string {
+	return "products out of stock: " + strings.Join(e.Ids, ", ")
+}
 
-	if result.Item == nil {
-		return nil, fmt.Errorf("product not found")
-	}
+// StockReservation is the quantity of a product that an order takes from its
+// stock.
+type StockReservation struct {
+	ProductId string
+	Quantity  int
+}
 
-	var product Product
-	err = attributevalue.UnmarshalMap(result.Item, &product)
-	if err != nil {
-		return nil, err
+// StockReservations sums the quantity of the items with StockReserved per
+// product, as lines with different options share the stock of their product.
+func StockReservations(items []OrderItem) []StockReservation {
+	var reservations []StockReservation
+	index := map[string]int{}
+	for _, item := range items {
+		if !item.StockReserved {
+			continue
+		}
+		if i, ok := index[item.ProductId]; ok {
+			reservations[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.ProductId] = len(reservations)
+		reservations = append(reservations, StockReservation{ProductId: item.ProductId, Quantity: item.Quantity})
 	}
-
-	return &product, nil
+	return reservations
 }
@@ -0,0 +1,140 @@
+package models
+
+// The repository interfaces separate the models from their storage. The
+// DynamoDB implementations live in the database package and an in-memory one
+// with the same semantics in database/memory.
+
+// Paginated listings fail with ErrInvalidCursor when the page cursor was not
+// issued for the same listing.
+
+// Create assigns an id to items without one. Update replaces a whole ad, but
+// only sets the fields admins edit on categories and products, so their SKU,
+// availability and stock are kept.
+
+type AdRepository interface {
+	ListAll(page PageRequest) (*Page[Ad], error)
+	// GetById fails with ErrAdNotFound.
+	GetById(adId string) (*Ad, error)
+	Create(ad Ad) (*Ad, error)
+	// Update fails with ErrAdNotFound.
+	Update(ad Ad) error
+	// Delete fails with ErrAdNotFound.
+	Delete(adId string) error
+}
+
+type CategoryRepository interface {
+	ListAll(page PageRequest) (*Page[Category], error)
+	// GetById fails with ErrCategoryNotFound.
+	GetById(categoryId string) (*Category, error)
+	Create(category Category) (*Category, error)
+	// Update sets the name and image of a category, keeping the SKU that
+	// links it to the catalog files. It fails with ErrCategoryNotFound.
+	Update(category Category) error
+	// Delete fails with ErrCategoryNotFound.
+	Delete(categoryId string) error
+	// BatchWrite puts and deletes categories in BatchWriteItem chunks. It is
+	// not atomic.
+	BatchWrite(put []Category, deleteIds []string) error
+}
+
+type ProductRepository interface {
+	ListAll(page PageRequest) (*Page[Product], error)
+	ListByCategory(categoryId string, page PageRequest) (*Page[Product], error)
+	// GetById fails with ErrProductNotFound.
+	GetById(productId string) (*Product, error)
+	// GetByIds reports every missing product in one *ProductsNotFoundError.
+	GetByIds(productIds []string) (map[string]Product, error)
+	Create(product Product) (*Product, error)
+	// Update replaces the catalog fields of a product, leaving its SKU,
+	// availability and stock alone. It fails with ErrProductNotFound.
+	Update(product Product) error
+	// SetAvailability sets whether a product can be ordered and its stock,
+	// where nil stops tracking stock. It fails with ErrProductNotFound.
+	SetAvailability(productId string, available bool, stockQuantity *int) error
+	// Delete fails with ErrProductNotFound.
+	Delete(productId string) error
+	// BatchWrite puts and deletes products in BatchWriteItem chunks. It is
+	// not atomic.
+	BatchWrite(put []Product, deleteIds []string) error
+}
+
+type DeliveryAddressRepository interface {
+	// GetById fails with ErrDeliveryAddressNotFound.
+	GetById(addressId string) (*DeliveryAddress, error)
+	Create(address DeliveryAddress) (*DeliveryAddress, error)
+	ListByUser(userId string) ([]DeliveryAddress, error)
+}
+
+type OrderRepository interface {
+	// Create stores an order, its items and its first history entry
+	// atomically, taking the quantity of items with StockReserved from the
+	// product's stock. It fails with ErrOrderTooLarge, ErrOrderAlreadyExists
+	// or *OutOfStockError.
+	Create(order Order, items []OrderItem, actor Actor) (*Order, []OrderItem, error)
+	// GetById fails with ErrOrderNotFound.
+	GetById(orderId string) (*Order, error)
+	// UpdateStatus moves an order to status and appends the change to its
+	// history atomically, returning restock to the products' stock in the
+	// same transaction. Products that were deleted or no longer track stock
+	// are skipped. It fails with *InvalidTransitionError when the transition
+	// is not allowed or the status changed concurrently.
+	UpdateStatus(orderId string, status OrderStatus, actor Actor, reason string, restock []StockReservation) (*Order, error)
+	// ListByUser returns a page of the orders of a user, newest first.
+	ListByUser(userId string, page PageRequest) (*Page[Order], error)
+	GetItems(orderId string) ([]OrderItem, error)
+	// GetStatusHistory returns the status changes of an order, oldest first.
+	GetStatusHistory(orderId string) ([]OrderStatusChange, error)
+}
+
+type UserRepository interface {
+	// Create fails with ErrPhoneAlreadyRegistered.
+	Create(user User) error
+	// GetById and GetByPhone fail with ErrUserNotFound.
+	GetById(userId string) (*User, error)
+	GetByPhone(phone string) (*User, error)
+	// UpdateOTP replaces a user's OTP state if the stored OTPVersion is
+	// state.OTPVersion-1. It fails with ErrUserNotFound or
+	// ErrOTPStateChanged.
+	UpdateOTP(userId string, state OTPState) error
+	// SetRoles fails with ErrUserNotFound.
+	SetRoles(userId string, roles []Role) error
+}
+
+type IdempotencyRepository interface {
+	// Claim records that a request with the given key and body hash is in
+	// progress. It fails with ErrIdempotencyKeyExists when an unexpired
+	// record for the key already exists.
+	Claim(id, requestHash string) error
+	// Get fails with ErrIdempotencyRecordNotFound.
+	Get(id string) (*IdempotencyRecord, error)
+	// Complete stores the response of a claimed request for replay.
+	Complete(record IdempotencyRecord) error
+	// Release deletes a claim so that the request can be retried.
+	Release(id string) error
+}
+
+type SessionRepository interface {
+	Create(session Session) error
+	// Get fails with ErrSessionNotFound.
+	Get(sessionId string) (*Session, error)
+	// Rotate replaces the session's token hash and expiry if its token hash
+	// is still previousHash. It fails with ErrSessionNotFound or
+	// ErrSessionTokenChanged.
+	Rotate(sessionId, previousHash, tokenHash string, expiresAt int64) error
+	// Delete ends a session; deleting a missing session is not an error.
+	Delete(sessionId string) error
+	// DeleteByUser ends every session of a user.
+	DeleteByUser(userId string) error
+}
+
+// Repositories bundles every repository the app depends on.
+type Repositories struct {
+	Ads               AdRepository
+	Categories        CategoryRepository
+	Products          ProductRepository
+	DeliveryAddresses DeliveryAddressRepository
+	Orders            OrderRepository
+	Users             UserRepository
+	Idempotency       IdempotencyRepository
+	Sessions          SessionRepository
+}
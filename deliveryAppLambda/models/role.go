@@ -0,0 +1,90 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Role string
+
+const (
+	RoleCustomer   Role = "customer"
+	RoleCourier    Role = "courier"
+	RoleStoreStaff Role = "store_staff"
+	RoleAdmin      Role = "admin"
+)
+
+var (
+	ErrUnknownRole = errors.New("unknown role")
+	ErrNoRoles     = errors.New("a user needs at least one role")
+)
+
+var knownRoles = map[Role]bool{
+	RoleCustomer:   true,
+	RoleCourier:    true,
+	RoleStoreStaff: true,
+	RoleAdmin:      true,
+}
+
+// ParseRoles validates role names and drops duplicates.
+func ParseRoles(names []string) ([]Role, error) {
+	var roles []Role
+	seen := map[Role]bool{}
+	for _, name := range names {
+		role := Role(name)
+		if !knownRoles[role] {
+			return nil, fmt.Errorf("%w %q", ErrUnknownRole, name)
+		}
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+
+	if len(roles) == 0 {
+		return nil, ErrNoRoles
+	}
+	return roles, nil
+}
+
+// RolesFromNames converts the role names of a token, dropping unknown ones.
+// Users and tokens from before roles existed have none and are customers.
+func RolesFromNames(names []string) []Role {
+	var roles []Role
+	for _, name := range names {
+		if role := Role(name); knownRoles[role] {
+			roles = append(roles, role)
+		}
+	}
+
+	if len(roles) == 0 {
+		return []Role{RoleCustomer}
+	}
+	return roles
+}
+
+func hasAnyRole(have []Role, want []Role) bool {
+	for _, role := range have {
+		for _, wanted := range want {
+			if role == wanted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetUserRoles replaces the roles of a user. It fails with ErrUserNotFound,
+// ErrNoRoles or an error wrapping ErrUnknownRole.
+func SetUserRoles(users UserRepository, userId string, names []string) ([]Role, error) {
+	roles, err := ParseRoles(names)
+	if err != nil {
+		return nil, err
+	}
+
+	err = users.SetRoles(userId, roles)
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
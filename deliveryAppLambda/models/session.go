@@ -0,0 +1,186 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshTokenTTL is how long a refresh token can be used. Every refresh
+// starts the period again.
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrSessionTokenChanged is returned by SessionRepository.Rotate when the
+	// session's refresh token was rotated since it was read.
+	ErrSessionTokenChanged = errors.New("session refresh token changed concurrently")
+
+	// ErrInvalidRefreshToken is returned for refresh tokens that are
+	// malformed, expired or belong to an ended session.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+	// ErrRefreshTokenReused is returned when a refresh token that was already
+	// rotated is presented again. The whole session is ended, since either
+	// the client or an attacker holds a stolen token.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// Session is a refresh token family: the chain of refresh tokens issued from
+// one login. Only the hash of the current token is stored; any earlier token
+// of the family presented again is a reuse.
+type Session struct {
+	Id        string `json:"id" dynamodbav:"id"`
+	UserId    string `json:"userId" dynamodbav:"userId"`
+	TokenHash string `json:"-" dynamodbav:"tokenHash"`
+	CreatedAt string `json:"createdAt" dynamodbav:"createdAt"`
+	ExpiresAt int64  `json:"expiresAt" dynamodbav:"expiresAt"`
+}
+
+func (s *Session) expired(now time.Time) bool {
+	return s.ExpiresAt < now.Unix()
+}
+
+// Refresh tokens are "<session id>.<secret>", so the session can be found
+// without storing the token itself.
+func newRefreshToken(sessionId string) (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return sessionId + "." + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func refreshTokenSession(token string) (string, error) {
+	sessionId, secret, found := strings.Cut(token, ".")
+	if !found || sessionId == "" || secret == "" {
+		return "", ErrInvalidRefreshToken
+	}
+	return sessionId, nil
+}
+
+// StartSession opens a session for a user who just logged in and returns its
+// first refresh token.
+func StartSession(sessions SessionRepository, userId string) (string, error) {
+	now := time.Now()
+	session := Session{
+		Id:        uuid.New().String(),
+		UserId:    userId,
+		CreatedAt: now.Format(time.RFC3339),
+		ExpiresAt: now.Add(RefreshTokenTTL).Unix(),
+	}
+
+	token, err := newRefreshToken(session.Id)
+	if err != nil {
+		return "", err
+	}
+	session.TokenHash = hashRefreshToken(token)
+
+	err = sessions.Create(session)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RefreshSession exchanges a refresh token for the next one of its family.
+// Presenting a token that was already exchanged ends the session and fails
+// with ErrRefreshTokenReused.
+func RefreshSession(sessions SessionRepository, token string) (*Session, string, error) {
+	sessionId, err := refreshTokenSession(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	session, err := sessions.Get(sessionId)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	if session.expired(now) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	tokenHash := hashRefreshToken(token)
+	if subtle.ConstantTimeCompare([]byte(tokenHash), []byte(session.TokenHash)) != 1 {
+		return nil, "", revokeReusedSession(sessions, session.Id)
+	}
+
+	next, err := newRefreshToken(session.Id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	session.TokenHash = hashRefreshToken(next)
+	session.ExpiresAt = now.Add(RefreshTokenTTL).Unix()
+	err = sessions.Rotate(session.Id, tokenHash, session.TokenHash, session.ExpiresAt)
+	if errors.Is(err, ErrSessionTokenChanged) {
+		// Another request exchanged the same token first.
+		return nil, "", revokeReusedSession(sessions, session.Id)
+	}
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return session, next, nil
+}
+
+func revokeReusedSession(sessions SessionRepository, sessionId string) error {
+	err := sessions.Delete(sessionId)
+	if err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// EndSession logs out the session a refresh token belongs to. Tokens of
+// sessions that already ended are accepted, so logging out is idempotent.
+func EndSession(sessions SessionRepository, token string) error {
+	sessionId, err := refreshTokenSession(token)
+	if err != nil {
+		return err
+	}
+
+	session, err := sessions.Get(sessionId)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only the holder of the current token may end the session, so that
+	// an old, leaked token cannot be used to log someone out.
+	if subtle.ConstantTimeCompare([]byte(hashRefreshToken(token)), []byte(session.TokenHash)) != 1 {
+		return ErrInvalidRefreshToken
+	}
+
+	return sessions.Delete(sessionId)
+}
+
+// EndAllSessions logs a user out of every device.
+func EndAllSessions(sessions SessionRepository, userId string) error {
+	return sessions.DeleteByUser(userId)
+}
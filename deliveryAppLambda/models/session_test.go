@@ -0,0 +1,103 @@
+package models_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/database/memory"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+)
+
+func TestRefreshSession(t *testing.T) {
+	tests := []struct {
+		name string
+		// present returns the token to refresh with, given the first token
+		// of the session and the one it was rotated to.
+		present       func(first, rotated string) string
+		wantErr       error
+		wantSessionOK bool
+	}{
+		{"current token", func(_, rotated string) string { return rotated }, nil, true},
+		{"rotated token reused", func(first, _ string) string { return first }, models.ErrRefreshTokenReused, false},
+		{"unknown session", func(string, string) string { return "unknown.secret" }, models.ErrInvalidRefreshToken, true},
+		{"malformed token", func(string, string) string { return "malformed" }, models.ErrInvalidRefreshToken, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := memory.NewSessionRepository()
+			first, err := models.StartSession(sessions, "user-1")
+			if err != nil {
+				t.Fatalf("StartSession() error = %v", err)
+			}
+			session, rotated, err := models.RefreshSession(sessions, first)
+			if err != nil {
+				t.Fatalf("RefreshSession() error = %v", err)
+			}
+
+			_, next, err := models.RefreshSession(sessions, tt.present(first, rotated))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RefreshSession() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (next == rotated || next == first) {
+				t.Errorf("RefreshSession() returned a token that was already issued")
+			}
+
+			_, err = sessions.Get(session.Id)
+			if sessionOK := err == nil; sessionOK != tt.wantSessionOK {
+				t.Errorf("session still exists = %v, want %v", sessionOK, tt.wantSessionOK)
+			}
+		})
+	}
+}
+
+func TestEndSession(t *testing.T) {
+	tests := []struct {
+		name          string
+		rotate        bool
+		presentFirst  bool
+		wantErr       error
+		wantSessionOK bool
+	}{
+		{"current token", false, false, nil, false},
+		{"token after rotation", true, false, nil, false},
+		{"rotated token", true, true, models.ErrInvalidRefreshToken, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := memory.NewSessionRepository()
+			first, err := models.StartSession(sessions, "user-1")
+			if err != nil {
+				t.Fatalf("StartSession() error = %v", err)
+			}
+			token := first
+			if tt.rotate {
+				_, token, err = models.RefreshSession(sessions, first)
+				if err != nil {
+					t.Fatalf("RefreshSession() error = %v", err)
+				}
+			}
+			if tt.presentFirst {
+				token = first
+			}
+
+			err = models.EndSession(sessions, token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EndSession() error = %v, want %v", err, tt.wantErr)
+			}
+
+			sessionId, _, _ := strings.Cut(first, ".")
+			_, err = sessions.Get(sessionId)
+			if sessionOK := err == nil; sessionOK != tt.wantSessionOK {
+				t.Errorf("session still exists = %v, want %v", sessionOK, tt.wantSessionOK)
+			}
+
+			// Logging out of an ended session is fine.
+			if !tt.wantSessionOK {
+				if err := models.EndSession(sessions, token); err != nil {
+					t.Errorf("second EndSession() error = %v", err)
+				}
+			}
+		})
+	}
+}
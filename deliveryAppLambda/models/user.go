@@ -1,30 +1,39 @@
 package models
 
 import (
-	"context"
 	"errors"
-	"fmt"
-	"os"
-	"strings"
 	"time"
 
-	"github.com/ZED-Magdy/delivery-cdk/lambda/database"
-	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+var (
+	ErrUserNotFound            = errors.New("user not found")
+	ErrPhoneAlreadyRegistered  = errors.New("phone number already registered")
+	ErrInvalidOTP              = errors.New("invalid OTP")
+	ErrOTPExpired              = errors.New("OTP expired")
+)
+
 type User struct {
 	ID          string    `json:"id" dynamodbav:"id"`
 	Name        string    `json:"name" dynamodbav:"name"`
 	Phone       string    `json:"phone" dynamodbav:"phone"`
-	OTP         string    `json:"otp,omitempty" dynamodbav:"otp"`
-	OTPExpiresAt time.Time `json:"otp_expires_at,omitempty" dynamodbav:"otp_expires_at"`
+	Roles       []Role    `json:"roles" dynamodbav:"roles,omitempty"`
+	OTPState
+}
+
+// RoleNames returns the names of the user's roles, as embedded in tokens.
+// Users registered before roles existed are customers.
+func (u *User) RoleNames() []string {
+	if len(u.Roles) == 0 {
+		return []string{string(RoleCustomer)}
+	}
+
+	names := make([]string, len(u.Roles))
+	for i, role := range u.Roles {
+		names[i] = string(role)
+	}
+	return names
 }
 
 type UserRegistrationInput struct {
@@ -41,318 +50,140 @@ type SendOTPInput struct {
 	Phone string `json:"phone" validate:"required"`
 }
 
-type Claims struct {
-	UserID string `json:"userId"`
-	Name   string `json:"name"`
-	Phone  string `json:"phone"`
-	jwt.RegisteredClaims
-}
-
-func RegisterUser(input UserRegistrationInput) (*User, error) {
-	usersTable := database.GetTables().UsersTable
-	ddbClient, err := database.NewDynamoDBClient(usersTable)
-	if err != nil {
-		return nil, err
+func RegisterUser(users UserRepository, sender OTPSender, input UserRegistrationInput) (*User, error) {
+	user := &User{
+		ID:    uuid.New().String(),
+		Name:  input.Name,
+		Phone: input.Phone,
+		Roles: []Role{RoleCustomer},
 	}
 
-	keyEx := expression.Key("phone").Equal(expression.Value(input.Phone))
-	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+	otp, err := issueOTP(user, time.Now())
 	if err != nil {
 		return nil, err
 	}
 
-	response, err := ddbClient.Client.Query(context.TODO(), &dynamodb.QueryInput{
-		TableName:              &ddbClient.Table,
-		IndexName:              aws.String("PhoneIndex"),
-		KeyConditionExpression: expr.KeyCondition(),
-		ExpressionAttributeNames: expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-	})
+	err = users.Create(*user)
 	if err != nil {
 		return nil, err
 	}
 
-	if response.Count > 0 {
-		return nil, errors.New("phone number already registered")
-	}
-
-	otp := "123456"
-
-	user := &User{
-		ID:          uuid.New().String(),
-		Name:        input.Name,
-		Phone:       input.Phone,
-		OTP:         otp,
-		OTPExpiresAt: time.Now().Add(2 * time.Minute),
-	}
-
-	item, err := attributevalue.MarshalMap(user)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal user: %w", err)
-	}
-
-	_, err = ddbClient.Client.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: &ddbClient.Table,
-		Item:      item,
-		ConditionExpression: aws.String("attribute_not_exists(phone)"),
-	})
-	
+	err = sender.SendOTP(user.Phone, otp)
 	if err != nil {
-		var conditionalCheckFailedErr *types.ConditionalCheckFailedException
-		if errors.As(err, &conditionalCheckFailedErr) {
-			return nil, errors.New("phone number already registered")
-		}
 		return nil, err
 	}
 
-	user.OTP = ""
+	user.OTPState = OTPState{}
 
 	return user, nil
 }
 
-func VerifyOTP(input OTPVerificationInput) (*User, error) {
-	usersTable := database.GetTables().UsersTable
-	ddbClient, err := database.NewDynamoDBClient(usersTable)
-	if err != nil {
-		return nil, err
-	}
-
-	keyEx := expression.Key("phone").Equal(expression.Value(input.Phone))
-	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+// VerifyOTP checks an OTP and fails with *OTPRateLimitError while
+// verification is locked after too many failed attempts. Every guess is
+// recorded before its result is returned, so parallel guesses cannot get
+// around the attempt limit.
+func VerifyOTP(users UserRepository, input OTPVerificationInput) (*User, error) {
+	found, err := users.GetByPhone(input.Phone)
 	if err != nil {
 		return nil, err
 	}
 
-	response, err := ddbClient.Client.Query(context.TODO(), &dynamodb.QueryInput{
-		TableName:              &ddbClient.Table,
-		IndexName:              aws.String("PhoneIndex"),
-		KeyConditionExpression: expr.KeyCondition(),
-		ExpressionAttributeNames: expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	if response.Count == 0 {
-		return nil, errors.New("user not found")
-	}
-
-	var users []User
-	err = attributevalue.UnmarshalListOfMaps(response.Items, &users)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(users) == 0 {
-		return nil, errors.New("user not found")
-	}
-
-	user := users[0]
+	for attempt := 0; attempt < otpUpdateAttempts; attempt++ {
+		user, err := users.GetById(found.ID)
+		if err != nil {
+			return nil, err
+		}
 
-	if user.OTP != input.OTP {
-		return nil, errors.New("invalid OTP")
+		user, err = verifyOTP(users, user, input.OTP, time.Now())
+		if errors.Is(err, ErrOTPStateChanged) {
+			continue
+		}
+		return user, err
 	}
 
-	if time.Now().After(user.OTPExpiresAt) {
-		return nil, errors.New("OTP expired")
-	}
+	return nil, errOTPContention
+}
 
-	updateExpr := expression.Set(expression.Name("otp"), expression.Value(""))
-	updateExpr = updateExpr.Set(expression.Name("otp_expires_at"), expression.Value(time.Time{}))
-	
-	expr, err = expression.NewBuilder().WithUpdate(updateExpr).Build()
-	if err != nil {
-		return nil, err
+func verifyOTP(users UserRepository, user *User, otp string, now time.Time) (*User, error) {
+	state := user.OTPState
+	if now.Before(state.OTPLockedUntil) {
+		return nil, &OTPRateLimitError{
+			Reason:     "too many failed OTP attempts",
+			RetryAfter: state.OTPLockedUntil.Sub(now),
+		}
 	}
 
-	_, err = ddbClient.Client.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
-		TableName: &ddbClient.Table,
-		Key: map[string]types.AttributeValue{
-			"id": &types.AttributeValueMemberS{Value: user.ID},
-		},
-		UpdateExpression:          expr.Update(),
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-	})
+	matches, err := CheckOTP(user.ID, otp, state.OTPHash)
 	if err != nil {
 		return nil, err
 	}
 
-	user.OTP = ""
-	user.OTPExpiresAt = time.Time{}
-
-	return &user, nil
-}
-
-func SendOTP(input SendOTPInput) error {
-	usersTable := database.GetTables().UsersTable
-	ddbClient, err := database.NewDynamoDBClient(usersTable)
-	if (err != nil) {
-		return err
-	}
-
-	keyEx := expression.Key("phone").Equal(expression.Value(input.Phone))
-	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
-	if (err != nil) {
-		return err
-	}
-
-	response, err := ddbClient.Client.Query(context.TODO(), &dynamodb.QueryInput{
-		TableName:              &ddbClient.Table,
-		IndexName:              aws.String("PhoneIndex"),
-		KeyConditionExpression: expr.KeyCondition(),
-		ExpressionAttributeNames: expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-	})
-	if (err != nil) {
-		return err
-	}
-
-	if (response.Count == 0) {
-		return errors.New("user not found")
-	}
-
-	var users []User
-	err = attributevalue.UnmarshalListOfMaps(response.Items, &users)
-	if (err != nil) {
-		return err
-	}
+	state.OTPVersion++
+	if !matches {
+		state.OTPFailedAttempts++
+		if state.OTPFailedAttempts >= MaxOTPAttempts {
+			// Burn the current code too, so a new one has to be requested
+			// once the lockout is over.
+			state.OTPHash = ""
+			state.OTPFailedAttempts = 0
+			state.OTPLockedUntil = now.Add(OTPLockoutDuration)
+		}
 
-	if (len(users) == 0) {
-		return errors.New("user not found")
+		err = users.UpdateOTP(user.ID, state)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidOTP
 	}
 
-	user := users[0]
-
-	otp := "123456"
-	otpExpiry := time.Now().Add(2 * time.Minute)
-
-	updateExpr := expression.Set(expression.Name("otp"), expression.Value(otp))
-	updateExpr = updateExpr.Set(expression.Name("otp_expires_at"), expression.Value(otpExpiry))
-	
-	expr, err = expression.NewBuilder().WithUpdate(updateExpr).Build()
-	if (err != nil) {
-		return err
+	if now.After(state.OTPExpiresAt) {
+		return nil, ErrOTPExpired
 	}
 
-	_, err = ddbClient.Client.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
-		TableName: &ddbClient.Table,
-		Key: map[string]types.AttributeValue{
-			"id": &types.AttributeValueMemberS{Value: user.ID},
-		},
-		UpdateExpression:          expr.Update(),
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-	})
-	
-	return err
-}
+	state.OTPHash = ""
+	state.OTPExpiresAt = time.Time{}
+	state.OTPFailedAttempts = 0
 
-func GetUserByID(userID string) (*User, error) {
-	usersTable := database.GetTables().UsersTable
-	ddbClient, err := database.NewDynamoDBClient(usersTable)
+	err = users.UpdateOTP(user.ID, state)
 	if err != nil {
 		return nil, err
 	}
 
-	response, err := ddbClient.Client.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: &ddbClient.Table,
-		Key: map[string]types.AttributeValue{
-			"id": &types.AttributeValueMemberS{Value: userID},
-		},
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	if len(response.Item) == 0 {
-		return nil, errors.New("user not found")
-	}
-
-	user := new(User)
-	err = attributevalue.UnmarshalMap(response.Item, user)
-	if err != nil {
-		return nil, err
-	}
+	user.OTPState = OTPState{}
 
 	return user, nil
 }
 
-func GetUserByPhone(phone string) (*User, error) {
-	usersTable := database.GetTables().UsersTable
-	ddbClient, err := database.NewDynamoDBClient(usersTable)
-	if err != nil {
-		return nil, err
-	}
-
-	keyEx := expression.Key("phone").Equal(expression.Value(phone))
-	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+// SendOTP sends a new OTP to a registered phone and fails with
+// *OTPRateLimitError during the resend cooldown, after the daily cap and
+// while verification is locked.
+func SendOTP(users UserRepository, sender OTPSender, input SendOTPInput) error {
+	found, err := users.GetByPhone(input.Phone)
 	if err != nil {
-		return nil, err
-	}
-
-	response, err := ddbClient.Client.Query(context.TODO(), &dynamodb.QueryInput{
-		TableName:              &ddbClient.Table,
-		IndexName:              aws.String("PhoneIndex"),
-		KeyConditionExpression: expr.KeyCondition(),
-		ExpressionAttributeNames: expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	if response.Count == 0 {
-		return nil, errors.New("user not found")
-	}
-
-	var users []User
-	err = attributevalue.UnmarshalListOfMaps(response.Items, &users)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(users) == 0 {
-		return nil, errors.New("user not found")
-	}
-
-	return &users[0], nil
-}
-
-func GetAuthUser(request events.APIGatewayProxyRequest) (*User, error) {
-	authHeader := request.Headers["Authorization"]
-	if authHeader == "" {
-		return nil, errors.New("authorization header is required")
+		return err
 	}
 
-	// Extract the token from the Authorization header
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		return nil, errors.New("authorization header format must be Bearer {token}")
-	}
-	tokenString := parts[1]
+	for attempt := 0; attempt < otpUpdateAttempts; attempt++ {
+		user, err := users.GetById(found.ID)
+		if err != nil {
+			return err
+		}
 
-	// Parse and validate the token
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
+		otp, err := issueOTP(user, time.Now())
+		if err != nil {
+			return err
 		}
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
 
-	if err != nil {
-		return nil, err
-	}
+		err = users.UpdateOTP(user.ID, user.OTPState)
+		if errors.Is(err, ErrOTPStateChanged) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
 
-	if !token.Valid {
-		return nil, errors.New("invalid token")
+		return sender.SendOTP(user.Phone, otp)
 	}
 
-	return &User{
-		ID:    claims.UserID,
-		Name:  claims.Name,
-		Phone: claims.Phone,
-	}, nil
-}
\ No newline at end of file
+	return errOTPContention
+}
@@ -6,9 +6,9 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/ZED-Magdy/delivery-cdk/lambda/clients"
 	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/aws/aws-sdk-go-v2/service/sns/types"
 )
@@ -20,20 +20,27 @@ type OrderNotification struct {
 	CustomerID string `json:"customerId"`
 }
 
-func SendOrderStatusNotification(orderId, status, userId string) error {
+// Notifier publishes order status notifications, looking up the order and
+// customer through its repositories.
+type Notifier struct {
+	Orders models.OrderRepository
+	Users  models.UserRepository
+}
+
+func (n *Notifier) SendOrderStatusNotification(orderId, status, userId string) error {
 	topicARN := os.Getenv("ORDER_STATUS_NOTIFICATION_TOPIC_ARN")
 	if topicARN == "" {
 		return fmt.Errorf("ORDER_STATUS_NOTIFICATION_TOPIC_ARN environment variable is not set")
 	}
 
 	// Get order details
-	order, err := models.GetOrderById(orderId)
+	order, err := n.Orders.GetById(orderId)
 	if err != nil {
 		return fmt.Errorf("failed to get order details: %v", err)
 	}
 
 	// Get user details for additional notification data if needed
-	user, err := models.GetUserByID(userId)
+	user, err := n.Users.GetById(userId)
 	if err != nil {
 		return fmt.Errorf("failed to get user details: %v", err)
 	}
@@ -52,12 +59,12 @@ func SendOrderStatusNotification(orderId, status, userId string) error {
 		return fmt.Errorf("failed to marshal notification: %v", err)
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+	c, err := clients.Get()
 	if err != nil {
-		return fmt.Errorf("failed to load AWS SDK config: %v", err)
+		return err
 	}
 
-	client := sns.NewFromConfig(cfg)
+	client := c.SNS
 	_, err = client.Publish(context.TODO(), &sns.PublishInput{
 		TopicArn: aws.String(topicARN),
 		Message:  aws.String(string(notificationJSON)),
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/clients"
+	"github.com/ZED-Magdy/delivery-cdk/lambda/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// NewOTPSender returns the OTP sender selected by OTP_SENDER: "sns" (the
+// default) sends SMS messages, "log" writes OTPs to the function log for
+// local development.
+func NewOTPSender() (models.OTPSender, error) {
+	switch sender := os.Getenv("OTP_SENDER"); sender {
+	case "", "sns":
+		return SNSOTPSender{SenderID: os.Getenv("OTP_SMS_SENDER_ID")}, nil
+	case "log":
+		return LogOTPSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown OTP_SENDER %q", sender)
+	}
+}
+
+func otpMessage(otp string) string {
+	return fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", otp, int(models.OTPTTL.Minutes()))
+}
+
+// SNSOTPSender sends OTPs as transactional SMS messages through SNS.
+type SNSOTPSender struct {
+	// SenderID is shown as the sender where the destination country
+	// supports it.
+	SenderID string
+}
+
+func (s SNSOTPSender) SendOTP(phone, otp string) error {
+	c, err := clients.Get()
+	if err != nil {
+		return err
+	}
+
+	attributes := map[string]types.MessageAttributeValue{
+		"AWS.SNS.SMS.SMSType": {
+			DataType:    aws.String("String"),
+			StringValue: aws.String("Transactional"),
+		},
+	}
+	if s.SenderID != "" {
+		attributes["AWS.SNS.SMS.SenderID"] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(s.SenderID),
+		}
+	}
+
+	_, err = c.SNS.Publish(context.TODO(), &sns.PublishInput{
+		PhoneNumber:       aws.String(phone),
+		Message:           aws.String(otpMessage(otp)),
+		MessageAttributes: attributes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send OTP: %v", err)
+	}
+
+	return nil
+}
+
+// LogOTPSender writes OTPs to the log instead of sending them. Never use it
+// in production.
+type LogOTPSender struct{}
+
+func (LogOTPSender) SendOTP(phone, otp string) error {
+	log.Printf("OTP for %s: %s", phone, otp)
+	return nil
+}
+
+// SentOTP is an OTP recorded by RecordingOTPSender.
+type SentOTP struct {
+	Phone string
+	OTP   string
+}
+
+// RecordingOTPSender records OTPs instead of sending them, so tests can read
+// back the code a user would have received. Err, when set, is returned from
+// every send.
+type RecordingOTPSender struct {
+	mu   sync.Mutex
+	sent []SentOTP
+	Err  error
+}
+
+func (s *RecordingOTPSender) SendOTP(phone, otp string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	s.sent = append(s.sent, SentOTP{Phone: phone, OTP: otp})
+	return nil
+}
+
+// Sent returns every recorded OTP, oldest first.
+func (s *RecordingOTPSender) Sent() []SentOTP {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SentOTP(nil), s.sent...)
+}
+
+// Last returns the most recent OTP sent to phone.
+func (s *RecordingOTPSender) Last(phone string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.sent) - 1; i >= 0; i-- {
+		if s.sent[i].Phone == phone {
+			return s.sent[i].OTP, true
+		}
+	}
+	return "", false
+}
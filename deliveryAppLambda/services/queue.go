@@ -6,9 +6,10 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/ZED-Magdy/delivery-cdk/lambda/clients"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
 type OrderMessage struct {
@@ -17,18 +18,30 @@ type OrderMessage struct {
 	UserId  string `json:"userId"`
 }
 
+// OrderQueue hands order status changes over for asynchronous processing.
+type OrderQueue interface {
+	SendOrder(orderId, status, userId string) error
+}
+
+// SQSOrderQueue sends orders to the queue at ORDER_QUEUE_URL.
+type SQSOrderQueue struct{}
+
+func (SQSOrderQueue) SendOrder(orderId, status, userId string) error {
+	return SendOrderToQueue(orderId, status, userId)
+}
+
 func SendOrderToQueue(orderId, status, userId string) error {
 	queueURL := os.Getenv("ORDER_QUEUE_URL")
 	if queueURL == "" {
 		return fmt.Errorf("ORDER_QUEUE_URL environment variable is not set")
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+	c, err := clients.Get()
 	if err != nil {
-		return fmt.Errorf("failed to load AWS SDK config: %v", err)
+		return err
 	}
 
-	client := sqs.NewFromConfig(cfg)
+	client := c.SQS
 
 	message := OrderMessage{
 		OrderId: orderId,
@@ -52,18 +65,54 @@ func SendOrderToQueue(orderId, status, userId string) error {
 	return nil
 }
 
-func ProcessOrdersFromQueue() error {
+// FailureReasonAttribute is the message attribute that carries the last
+// processing error of a message moved to the dead-letter queue.
+const FailureReasonAttribute = "FailureReason"
+
+// SendOrderToDeadLetterQueue moves a message body that could not be processed
+// to the order dead-letter queue along with the reason it failed.
+func SendOrderToDeadLetterQueue(messageBody, reason string) error {
+	queueURL := os.Getenv("ORDER_DLQ_URL")
+	if queueURL == "" {
+		return fmt.Errorf("ORDER_DLQ_URL environment variable is not set")
+	}
+
+	c, err := clients.Get()
+	if err != nil {
+		return err
+	}
+
+	client := c.SQS
+
+	_, err = client.SendMessage(context.TODO(), &sqs.SendMessageInput{
+		QueueUrl:    aws.String(queueURL),
+		MessageBody: aws.String(messageBody),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			FailureReasonAttribute: {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(reason),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send message to dead-letter queue: %v", err)
+	}
+
+	return nil
+}
+
+func (n *Notifier) ProcessOrdersFromQueue() error {
 	queueURL := os.Getenv("ORDER_QUEUE_URL")
 	if queueURL == "" {
 		return fmt.Errorf("ORDER_QUEUE_URL environment variable is not set")
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+	c, err := clients.Get()
 	if err != nil {
-		return fmt.Errorf("failed to load AWS SDK config: %v", err)
+		return err
 	}
 
-	client := sqs.NewFromConfig(cfg)
+	client := c.SQS
 
 	result, err := client.ReceiveMessage(context.TODO(), &sqs.ReceiveMessageInput{
 		QueueUrl:            aws.String(queueURL),
@@ -86,7 +135,7 @@ func ProcessOrdersFromQueue() error {
 		fmt.Printf("Processing order %s with status %s\n", orderMsg.OrderId, orderMsg.Status)
 		
 		// Send notification about the order status
-		err = SendOrderStatusNotification(orderMsg.OrderId, orderMsg.Status, orderMsg.UserId)
+		err = n.SendOrderStatusNotification(orderMsg.OrderId, orderMsg.Status, orderMsg.UserId)
 		if err != nil {
 			fmt.Printf("Failed to send notification: %v\n", err)
 			// Continue processing other messages even if notification fails
@@ -105,7 +154,7 @@ func ProcessOrdersFromQueue() error {
 	return nil
 }
 
-func ProcessOrderFromMessage(messageBody string) error {
+func (n *Notifier) ProcessOrderFromMessage(messageBody string) error {
 	var orderMsg OrderMessage
 	err := json.Unmarshal([]byte(messageBody), &orderMsg)
 	if err != nil {
@@ -116,5 +165,5 @@ func ProcessOrderFromMessage(messageBody string) error {
 	fmt.Printf("Processing order %s with status %s\n", orderMsg.OrderId, orderMsg.Status)
 	
 	// Send notification about the order status
-	return SendOrderStatusNotification(orderMsg.OrderId, orderMsg.Status, orderMsg.UserId)
+	return n.SendOrderStatusNotification(orderMsg.OrderId, orderMsg.Status, orderMsg.UserId)
 }
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed, were signed
+// with another key or belong to another scope.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SignCursor encodes a pagination cursor as base64url(payload).base64url(mac),
+// where the HMAC covers the scope and the payload. Binding the scope (e.g. the
+// list and the user it belongs to) stops a cursor from being replayed against
+// another listing.
+func SignCursor(scope string, payload []byte) (string, error) {
+	mac, err := cursorMAC(scope, payload)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac), nil
+}
+
+// VerifyCursor checks a cursor produced by SignCursor for the same scope and
+// returns its payload.
+func VerifyCursor(scope, cursor string) ([]byte, error) {
+	encodedPayload, encodedMAC, found := strings.Cut(cursor, ".")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	expected, err := cursorMAC(scope, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal(mac, expected) {
+		return nil, ErrInvalidCursor
+	}
+
+	return payload, nil
+}
+
+func cursorMAC(scope string, payload []byte) ([]byte, error) {
+	secret, err := CursorSigningSecret.Value()
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerifyCursor(t *testing.T) {
+	t.Setenv("CURSOR_SIGNING_SECRET_ARN", "")
+	t.Setenv("CURSOR_SIGNING_SECRET", "test-cursor-secret")
+
+	cursor, err := SignCursor("orders#user-1", []byte(`{"id":"order-1"}`))
+	if err != nil {
+		t.Fatalf("SignCursor() error = %v", err)
+	}
+	payload, mac, _ := strings.Cut(cursor, ".")
+
+	tests := []struct {
+		name    string
+		scope   string
+		cursor  string
+		secret  string
+		wantErr bool
+	}{
+		{"valid", "orders#user-1", cursor, "", false},
+		{"other scope", "orders#user-2", cursor, "", true},
+		{"tampered payload", "orders#user-1", "eyJpZCI6Im9yZGVyLTIifQ." + mac, "", true},
+		{"tampered mac", "orders#user-1", payload + "." + strings.Repeat("A", len(mac)), "", true},
+		{"missing mac", "orders#user-1", payload, "", true},
+		{"not base64", "orders#user-1", "!!!." + mac, "", true},
+		{"other key", "orders#user-1", cursor, "rotated-cursor-secret", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.secret != "" {
+				t.Setenv("CURSOR_SIGNING_SECRET", tt.secret)
+			}
+
+			got, err := VerifyCursor(tt.scope, tt.cursor)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCursor) {
+					t.Errorf("VerifyCursor() error = %v, want %v", err, ErrInvalidCursor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyCursor() error = %v", err)
+			}
+			if string(got) != `{"id":"order-1"}` {
+				t.Errorf("VerifyCursor() = %s", got)
+			}
+		})
+	}
+}
+
+func TestSignCursorWithoutSecret(t *testing.T) {
+	t.Setenv("CURSOR_SIGNING_SECRET_ARN", "")
+	t.Setenv("CURSOR_SIGNING_SECRET", "")
+
+	if _, err := SignCursor("orders#user-1", []byte("1")); err == nil {
+		t.Error("SignCursor() signed a cursor without a secret")
+	}
+}
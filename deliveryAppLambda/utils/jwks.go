@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sort"
+)
+
+// JSONWebKey is the public part of an asymmetric signing key (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns the public keys of every asymmetric key in the key set, so
+// other services can verify our tokens. HMAC keys are secret and never
+// published.
+func JWKS() (*JSONWebKeySet, error) {
+	ks, err := signingKeys(false)
+	if err != nil {
+		return nil, err
+	}
+
+	kids := make([]string, 0, len(ks.Keys))
+	for kid := range ks.Keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := &JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, kid := range kids {
+		key := ks.Keys[kid]
+		jwk := JSONWebKey{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encodeBigInt(public.N, 0)
+			jwk.E = encodeBigInt(big.NewInt(int64(public.E)), 0)
+		case *ecdsa.PublicKey:
+			size := (public.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = public.Curve.Params().Name
+			jwk.X = encodeBigInt(public.X, size)
+			jwk.Y = encodeBigInt(public.Y, size)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set, nil
+}
+
+// encodeBigInt base64url-encodes n big-endian, left-padded to size bytes.
+func encodeBigInt(n *big.Int, size int) string {
+	b := n.Bytes()
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -2,28 +2,51 @@ package utils
 
 import (
 	"errors"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// AccessTokenTTL is the lifetime of access tokens. They are renewed with a
+// refresh token, so it is kept short to limit how long a token outlives a
+// logout.
+const AccessTokenTTL = 15 * time.Minute
+
+// verificationAlgorithms are the algorithms ValidateToken accepts.
+var verificationAlgorithms = []string{
+	jwt.SigningMethodHS256.Alg(),
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodES256.Alg(),
+}
+
 type JwtClaims struct {
-	UserID string `json:"userId"`
-	Phone  string `json:"phone"`
-	Name   string `json:"name"`
+	UserID string   `json:"userId"`
+	Phone  string   `json:"phone"`
+	Name   string   `json:"name"`
+	Roles  []string `json:"roles,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userId, name, phone string) (string, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
+// GenerateJWT signs an access token with the active key of the configured
+// algorithm and names the key in the kid header.
+func GenerateJWT(userId, name, phone string, roles []string) (string, error) {
+	ks, err := signingKeys(false)
+	if err != nil {
+		return "", err
+	}
+
+	kid, key, err := ks.active(SigningAlgorithm())
+	if err != nil {
+		return "", err
+	}
 
 	claims := JwtClaims{
 		UserID: userId,
 		Phone:  phone,
 		Name:   name,
+		Roles:  roles,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "delivery-app",
@@ -31,8 +54,9 @@ func GenerateJWT(userId, name, phone string) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = kid
+	tokenString, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", err
 	}
@@ -40,21 +64,32 @@ func GenerateJWT(userId, name, phone string) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken verifies the JWT token and returns the claims if valid
+// ValidateToken verifies the JWT token against the key named by its kid
+// header, accepting HS256, RS256 and ES256 keys, and returns the claims if
+// valid
 func ValidateToken(tokenString string) (*JwtClaims, error) {
 	if tokenString == "" {
 		return nil, errors.New("token is required")
 	}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	
 	token, err := jwt.ParseWithClaims(tokenString, &JwtClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no kid header")
+		}
+
+		key, err := verificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// Validate the signing method against the key, so a token cannot
+		// pick an algorithm its key was not made for
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, errors.New("invalid signing method")
 		}
-		return []byte(jwtSecret), nil
-	})
+		return key.Public, nil
+	}, jwt.WithValidMethods(verificationAlgorithms))
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// GenerateSigningKey returns a new PEM private key for RS256 or ES256.
+func GenerateSigningKey(alg string) (string, error) {
+	var key interface{}
+	var err error
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodES256.Alg():
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return "", fmt.Errorf("cannot generate %s keys", alg)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// EnsureActiveKey makes sure the key set secret names a usable active key for
+// alg, generating one and making it active when it does not. It returns the
+// secret to store and whether it changed. HS256 keys are never generated; the
+// stack seeds the first one.
+func EnsureActiveKey(secret, alg string) (string, bool, error) {
+	ks, err := ParseKeySet(secret)
+	if err != nil {
+		return "", false, err
+	}
+	if _, _, err := ks.active(alg); err == nil {
+		return secret, false, nil
+	}
+
+	var members map[string]string
+	if err := json.Unmarshal([]byte(secret), &members); err != nil {
+		return "", false, fmt.Errorf("invalid JWT key set: %v", err)
+	}
+
+	key, err := GenerateSigningKey(alg)
+	if err != nil {
+		return "", false, err
+	}
+
+	kid := strings.ToLower(alg) + "-" + uuid.New().String()[:8]
+	members[kid] = key
+	members[activeKidMember+alg] = kid
+
+	updated, err := json.Marshal(members)
+	if err != nil {
+		return "", false, err
+	}
+	return string(updated), true, nil
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestEnsureActiveKey(t *testing.T) {
+	seeded := `{"activeKid":"initial","initial":"hmac-secret"}`
+	withRSA, _, err := EnsureActiveKey(seeded, "RS256")
+	if err != nil {
+		t.Fatalf("EnsureActiveKey(RS256) error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		secret      string
+		alg         string
+		wantChanged bool
+		wantErr     bool
+	}{
+		{"generates RS256 key", seeded, "RS256", true, false},
+		{"generates ES256 key", seeded, "ES256", true, false},
+		{"keeps active RS256 key", withRSA, "RS256", false, false},
+		{"adds ES256 next to RS256", withRSA, "ES256", true, false},
+		{"keeps seeded HS256 key", seeded, "HS256", false, false},
+		{"never generates HS256 keys", `{"2024-06":"hmac-secret"}`, "HS256", false, true},
+		{"rejects malformed key set", `not json`, "RS256", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, changed, err := EnsureActiveKey(tt.secret, tt.alg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnsureActiveKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("EnsureActiveKey() changed = %v, want %v", changed, tt.wantChanged)
+			}
+
+			ks, err := ParseKeySet(updated)
+			if err != nil {
+				t.Fatalf("ParseKeySet() error = %v", err)
+			}
+			if _, _, err := ks.active(tt.alg); err != nil {
+				t.Errorf("no active %s key: %v", tt.alg, err)
+			}
+			if _, _, err := ks.active("HS256"); err != nil {
+				t.Errorf("existing HS256 key lost: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ZED-Magdy/delivery-cdk/lambda/clients"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// Secret is a key kept in Secrets Manager. It is read from the secret at the
+// ARN in ARNVariable, and kept for the life of the container, or taken from
+// ValueVariable when no ARN is set, for local development and tests.
+type Secret struct {
+	ARNVariable   string
+	ValueVariable string
+
+	mu    sync.Mutex
+	value []byte
+}
+
+// CursorSigningSecret signs pagination cursors.
+var CursorSigningSecret = &Secret{
+	ARNVariable:   "CURSOR_SIGNING_SECRET_ARN",
+	ValueVariable: "CURSOR_SIGNING_SECRET",
+}
+
+// OTPHashSecret keys the hashes of the OTPs stored on users.
+var OTPHashSecret = &Secret{
+	ARNVariable:   "OTP_HASH_SECRET_ARN",
+	ValueVariable: "OTP_HASH_SECRET",
+}
+
+// Value returns the secret, loading it on first use. A failed load is retried
+// on the next call.
+func (s *Secret) Value() ([]byte, error) {
+	secretARN := os.Getenv(s.ARNVariable)
+	if secretARN == "" {
+		value := os.Getenv(s.ValueVariable)
+		if value == "" {
+			return nil, fmt.Errorf("neither %s nor %s is set", s.ARNVariable, s.ValueVariable)
+		}
+		return []byte(value), nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.value != nil {
+		return s.value, nil
+	}
+
+	c, err := clients.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.SecretsManager.GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretARN),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %v", s.ARNVariable, err)
+	}
+
+	value := aws.ToString(output.SecretString)
+	if value == "" {
+		return nil, fmt.Errorf("secret at %s is empty", s.ARNVariable)
+	}
+	s.value = []byte(value)
+	return s.value, nil
+}